@@ -0,0 +1,127 @@
+package areas
+
+func province(code, th, en string, aliases ...string) Area {
+	return Area{Code: code, Level: LevelProvince, NameTH: th, NameEN: en, Aliases: aliases}
+}
+
+func district(parent, code, th, en string, aliases ...string) Area {
+	return Area{Code: code, Level: LevelDistrict, NameTH: th, NameEN: en, Parent: parent, Aliases: aliases}
+}
+
+func subdistrict(parent, code, th, en string, aliases ...string) Area {
+	return Area{Code: code, Level: LevelSubdistrict, NameTH: th, NameEN: en, Parent: parent, Aliases: aliases}
+}
+
+// Codes follow the Department of Provincial Administration numbering
+// (2-digit province, 4-digit district, 6-digit subdistrict). Districts and
+// subdistricts are only listed for the flood-affected areas we serve.
+var thaiAreas = []Area{
+	province("10", "กรุงเทพมหานคร", "Bangkok", "กรุงเทพ", "กทม"),
+	province("11", "สมุทรปราการ", "Samut Prakan"),
+	province("12", "นนทบุรี", "Nonthaburi"),
+	province("13", "ปทุมธานี", "Pathum Thani"),
+	province("14", "พระนครศรีอยุธยา", "Phra Nakhon Si Ayutthaya", "อยุธยา", "Ayutthaya"),
+	province("15", "อ่างทอง", "Ang Thong"),
+	province("16", "ลพบุรี", "Lop Buri", "Lopburi"),
+	province("17", "สิงห์บุรี", "Sing Buri"),
+	province("18", "ชัยนาท", "Chai Nat"),
+	province("19", "สระบุรี", "Saraburi"),
+	province("20", "ชลบุรี", "Chon Buri", "Chonburi"),
+	province("21", "ระยอง", "Rayong"),
+	province("22", "จันทบุรี", "Chanthaburi"),
+	province("23", "ตราด", "Trat"),
+	province("24", "ฉะเชิงเทรา", "Chachoengsao"),
+	province("25", "ปราจีนบุรี", "Prachin Buri", "Prachinburi"),
+	province("26", "นครนายก", "Nakhon Nayok"),
+	province("27", "สระแก้ว", "Sa Kaeo"),
+	province("30", "นครราชสีมา", "Nakhon Ratchasima", "โคราช", "Korat"),
+	province("31", "บุรีรัมย์", "Buri Ram", "Buriram"),
+	province("32", "สุรินทร์", "Surin"),
+	province("33", "ศรีสะเกษ", "Si Sa Ket", "Sisaket"),
+	province("34", "อุบลราชธานี", "Ubon Ratchathani"),
+	province("35", "ยโสธร", "Yasothon"),
+	province("36", "ชัยภูมิ", "Chaiyaphum"),
+	province("37", "อำนาจเจริญ", "Amnat Charoen"),
+	province("38", "บึงกาฬ", "Bueng Kan"),
+	province("39", "หนองบัวลำภู", "Nong Bua Lam Phu"),
+	province("40", "ขอนแก่น", "Khon Kaen"),
+	province("41", "อุดรธานี", "Udon Thani"),
+	province("42", "เลย", "Loei"),
+	province("43", "หนองคาย", "Nong Khai"),
+	province("44", "มหาสารคาม", "Maha Sarakham"),
+	province("45", "ร้อยเอ็ด", "Roi Et"),
+	province("46", "กาฬสินธุ์", "Kalasin"),
+	province("47", "สกลนคร", "Sakon Nakhon"),
+	province("48", "นครพนม", "Nakhon Phanom"),
+	province("49", "มุกดาหาร", "Mukdahan"),
+	province("50", "เชียงใหม่", "Chiang Mai"),
+	province("51", "ลำพูน", "Lamphun"),
+	province("52", "ลำปาง", "Lampang"),
+	province("53", "อุตรดิตถ์", "Uttaradit"),
+	province("54", "แพร่", "Phrae"),
+	province("55", "น่าน", "Nan"),
+	province("56", "พะเยา", "Phayao"),
+	province("57", "เชียงราย", "Chiang Rai"),
+	province("58", "แม่ฮ่องสอน", "Mae Hong Son"),
+	province("60", "นครสวรรค์", "Nakhon Sawan"),
+	province("61", "อุทัยธานี", "Uthai Thani"),
+	province("62", "กำแพงเพชร", "Kamphaeng Phet"),
+	province("63", "ตาก", "Tak"),
+	province("64", "สุโขทัย", "Sukhothai"),
+	province("65", "พิษณุโลก", "Phitsanulok"),
+	province("66", "พิจิตร", "Phichit"),
+	province("67", "เพชรบูรณ์", "Phetchabun"),
+	province("70", "ราชบุรี", "Ratchaburi"),
+	province("71", "กาญจนบุรี", "Kanchanaburi"),
+	province("72", "สุพรรณบุรี", "Suphan Buri"),
+	province("73", "นครปฐม", "Nakhon Pathom"),
+	province("74", "สมุทรสาคร", "Samut Sakhon"),
+	province("75", "สมุทรสงคราม", "Samut Songkhram"),
+	province("76", "เพชรบุรี", "Phetchaburi"),
+	province("77", "ประจวบคีรีขันธ์", "Prachuap Khiri Khan"),
+	province("80", "นครศรีธรรมราช", "Nakhon Si Thammarat", "นครศรีฯ"),
+	province("81", "กระบี่", "Krabi"),
+	province("82", "พังงา", "Phang Nga"),
+	province("83", "ภูเก็ต", "Phuket"),
+	province("84", "สุราษฎร์ธานี", "Surat Thani"),
+	province("85", "ระนอง", "Ranong"),
+	province("86", "ชุมพร", "Chumphon"),
+	province("90", "สงขลา", "Songkhla"),
+	province("91", "สตูล", "Satun"),
+	province("92", "ตรัง", "Trang"),
+	province("93", "พัทลุง", "Phatthalung"),
+	province("94", "ปัตตานี", "Pattani"),
+	province("95", "ยะลา", "Yala"),
+	province("96", "นราธิวาส", "Narathiwat"),
+
+	district("90", "9001", "เมืองสงขลา", "Mueang Songkhla"),
+	district("90", "9002", "สทิงพระ", "Sathing Phra"),
+	district("90", "9003", "จะนะ", "Chana"),
+	district("90", "9004", "นาทวี", "Na Thawi"),
+	district("90", "9005", "เทพา", "Thepha"),
+	district("90", "9006", "สะบ้าย้อย", "Saba Yoi"),
+	district("90", "9007", "ระโนด", "Ranot"),
+	district("90", "9008", "กระแสสินธุ์", "Krasae Sin"),
+	district("90", "9009", "รัตภูมิ", "Rattaphum"),
+	district("90", "9010", "สะเดา", "Sadao"),
+	district("90", "9011", "หาดใหญ่", "Hat Yai"),
+	district("90", "9012", "นาหม่อม", "Na Mom"),
+	district("90", "9013", "ควนเนียง", "Khuan Niang"),
+	district("90", "9014", "บางกล่ำ", "Bang Klam"),
+	district("90", "9015", "สิงหนคร", "Singhanakhon"),
+	district("90", "9016", "คลองหอยโข่ง", "Khlong Hoi Khong"),
+
+	subdistrict("9011", "901101", "หาดใหญ่", "Hat Yai"),
+	subdistrict("9011", "901102", "ควนลัง", "Khuan Lang"),
+	subdistrict("9011", "901103", "คูเต่า", "Khu Tao"),
+	subdistrict("9011", "901104", "คอหงส์", "Kho Hong"),
+	subdistrict("9011", "901105", "คลองแห", "Khlong Hae"),
+	subdistrict("9011", "901107", "คลองอู่ตะเภา", "Khlong U Taphao"),
+	subdistrict("9011", "901108", "ฉลุง", "Chalung"),
+	subdistrict("9011", "901111", "ทุ่งใหญ่", "Thung Yai"),
+	subdistrict("9011", "901112", "ทุ่งตำเสา", "Thung Tam Sao"),
+	subdistrict("9011", "901113", "ท่าข้าม", "Tha Kham"),
+	subdistrict("9011", "901114", "น้ำน้อย", "Nam Noi"),
+	subdistrict("9011", "901116", "บ้านพรุ", "Ban Phru"),
+	subdistrict("9011", "901118", "พะตง", "Phatong"),
+}
@@ -0,0 +1,177 @@
+package areas
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/Nxdus/hatyai-api/textnorm"
+)
+
+type Level string
+
+const (
+	LevelProvince    Level = "province"
+	LevelDistrict    Level = "district"
+	LevelSubdistrict Level = "subdistrict"
+)
+
+type Area struct {
+	Code    string   `json:"code"`
+	Level   Level    `json:"level"`
+	NameTH  string   `json:"name_th"`
+	NameEN  string   `json:"name_en"`
+	Parent  string   `json:"parent,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+type Gazetteer struct {
+	byCode map[string]*Area
+	byKey  map[Level]map[string][]*Area
+}
+
+func NewGazetteer(list []Area) *Gazetteer {
+	g := &Gazetteer{
+		byCode: make(map[string]*Area, len(list)),
+		byKey:  make(map[Level]map[string][]*Area),
+	}
+	for i := range list {
+		a := &list[i]
+		g.byCode[a.Code] = a
+		if g.byKey[a.Level] == nil {
+			g.byKey[a.Level] = make(map[string][]*Area)
+		}
+		for _, name := range a.names() {
+			key := Key(name)
+			if key == "" {
+				continue
+			}
+			g.byKey[a.Level][key] = appendUnique(g.byKey[a.Level][key], a)
+		}
+	}
+	return g
+}
+
+var defaultGazetteer = NewGazetteer(thaiAreas)
+
+func Default() *Gazetteer {
+	return defaultGazetteer
+}
+
+func (g *Gazetteer) ByCode(code string) (*Area, bool) {
+	a, ok := g.byCode[strings.TrimSpace(code)]
+	return a, ok
+}
+
+// Resolve finds the area at the given level whose Thai name, romanised name
+// or alias matches name once prefixes, case and spacing are normalised. A
+// name shared by several areas does not resolve; Candidates lists them.
+func (g *Gazetteer) Resolve(level Level, name string) (*Area, bool) {
+	found := g.Candidates(level, name)
+	if len(found) != 1 {
+		return nil, false
+	}
+	return found[0], true
+}
+
+// Candidates returns every area at the given level that name could refer
+// to: the area with that code, or else those whose names match as in
+// Resolve.
+func (g *Gazetteer) Candidates(level Level, name string) []*Area {
+	if a, ok := g.byCode[strings.TrimSpace(name)]; ok && a.Level == level {
+		return []*Area{a}
+	}
+	return g.byKey[level][Key(name)]
+}
+
+// ResolveUnder is Resolve among the areas whose parent is parent, so a
+// district name shared by several provinces finds the one in parent.
+func (g *Gazetteer) ResolveUnder(parent *Area, level Level, name string) (*Area, bool) {
+	if a, ok := g.byCode[strings.TrimSpace(name)]; ok && a.Level == level && a.Parent == parent.Code {
+		return a, true
+	}
+	for _, a := range g.byKey[level][Key(name)] {
+		if a.Parent == parent.Code {
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+// Matches reports whether a raw upstream name refers to the given area.
+func (g *Gazetteer) Matches(a *Area, name string) bool {
+	key := Key(name)
+	if key == "" {
+		return false
+	}
+	for _, candidate := range g.byKey[a.Level][key] {
+		if candidate == a {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Area) names() []string {
+	names := make([]string, 0, len(a.Aliases)+2)
+	names = append(names, a.NameTH, a.NameEN)
+	return append(names, a.Aliases...)
+}
+
+// Prefixes that upstream and users put in front of admin-area names. Longer
+// forms come first so "อำเภอ" is stripped before "อ.".
+var namePrefixes = []string{
+	"จังหวัด",
+	"กิ่งอำเภอ",
+	"อำเภอ",
+	"ตำบล",
+	"แขวง",
+	"เขต",
+	"จ.",
+	"อ.",
+	"ต.",
+	"changwat ",
+	"amphoe ",
+	"amphur ",
+	"tambon ",
+}
+
+var nameSuffixes = []string{
+	" province",
+	" district",
+	" subdistrict",
+}
+
+// Key returns the comparison form of an area name: lower case, common
+// administrative prefixes removed, and all spacing and punctuation dropped.
+func Key(name string) string {
+	name = textnorm.Normalize(name)
+
+	for _, p := range namePrefixes {
+		if strings.HasPrefix(name, p) {
+			name = strings.TrimSpace(strings.TrimPrefix(name, p))
+			break
+		}
+	}
+	for _, s := range nameSuffixes {
+		if strings.HasSuffix(name, s) {
+			name = strings.TrimSpace(strings.TrimSuffix(name, s))
+			break
+		}
+	}
+
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '.' || r == '-' || r == '\'' {
+			return -1
+		}
+		return r
+	}, name)
+}
+
+func appendUnique(list []*Area, a *Area) []*Area {
+	for _, existing := range list {
+		if existing == a {
+			return list
+		}
+	}
+	return append(list, a)
+}
@@ -0,0 +1,45 @@
+package areas
+
+import "testing"
+
+func TestResolveAmbiguous(t *testing.T) {
+	gz := NewGazetteer([]Area{
+		{Code: "10", Level: LevelProvince, NameTH: "ก", NameEN: "Ko"},
+		{Code: "20", Level: LevelProvince, NameTH: "ข", NameEN: "Kho"},
+		{Code: "1001", Level: LevelDistrict, NameTH: "เมือง", NameEN: "Mueang", Parent: "10"},
+		{Code: "2001", Level: LevelDistrict, NameTH: "เมือง", NameEN: "Mueang", Parent: "20"},
+		{Code: "2002", Level: LevelDistrict, NameTH: "ท่าข้าม", NameEN: "Tha Kham", Parent: "20"},
+	})
+	parent, _ := gz.ByCode("20")
+
+	tests := []struct {
+		name           string
+		parent         *Area
+		query          string
+		wantCode       string
+		wantCandidates int
+	}{
+		{"unique name", nil, "Tha Kham", "2002", 1},
+		{"shared name", nil, "อ.เมือง", "", 2},
+		{"code of a shared name", nil, "2001", "2001", 1},
+		{"shared name within parent", parent, "Mueang", "2001", 2},
+		{"unknown name", nil, "Atlantis", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var area *Area
+			var ok bool
+			if tt.parent != nil {
+				area, ok = gz.ResolveUnder(tt.parent, LevelDistrict, tt.query)
+			} else {
+				area, ok = gz.Resolve(LevelDistrict, tt.query)
+			}
+			if ok != (tt.wantCode != "") || ok && area.Code != tt.wantCode {
+				t.Errorf("resolved %v, %v; want code %q", area, ok, tt.wantCode)
+			}
+			if got := len(gz.Candidates(LevelDistrict, tt.query)); got != tt.wantCandidates {
+				t.Errorf("Candidates = %d, want %d", got, tt.wantCandidates)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package areas
+
+import "strings"
+
+// Region is one of the six geographic regions of Thailand, made up of whole
+// provinces.
+type Region struct {
+	ID        string   `json:"id"`
+	NameTH    string   `json:"name_th"`
+	NameEN    string   `json:"name_en"`
+	Provinces []string `json:"provinces"`
+}
+
+// RegionAll stands for the whole country wherever a region id is accepted.
+const RegionAll = "all"
+
+var regions = []Region{
+	{ID: "north", NameTH: "ภาคเหนือ", NameEN: "Northern", Provinces: []string{"50", "51", "52", "53", "54", "55", "56", "57", "58"}},
+	{ID: "northeast", NameTH: "ภาคตะวันออกเฉียงเหนือ", NameEN: "Northeastern", Provinces: []string{
+		"30", "31", "32", "33", "34", "35", "36", "37", "38", "39",
+		"40", "41", "42", "43", "44", "45", "46", "47", "48", "49",
+	}},
+	{ID: "central", NameTH: "ภาคกลาง", NameEN: "Central", Provinces: []string{
+		"10", "11", "12", "13", "14", "15", "16", "17", "18", "19", "26",
+		"60", "61", "62", "64", "65", "66", "67", "72", "73", "74", "75",
+	}},
+	{ID: "east", NameTH: "ภาคตะวันออก", NameEN: "Eastern", Provinces: []string{"20", "21", "22", "23", "24", "25", "27"}},
+	{ID: "west", NameTH: "ภาคตะวันตก", NameEN: "Western", Provinces: []string{"63", "70", "71", "76", "77"}},
+	{ID: "south", NameTH: "ภาคใต้", NameEN: "Southern", Provinces: []string{
+		"80", "81", "82", "83", "84", "85", "86", "90", "91", "92", "93", "94", "95", "96",
+	}},
+}
+
+func Regions() []Region {
+	return regions
+}
+
+// RegionByID finds a region by id, or by its Thai or English name.
+func RegionByID(id string) (*Region, bool) {
+	key := Key(id)
+	for i := range regions {
+		r := &regions[i]
+		if key == r.ID || key == Key(r.NameTH) || key == Key(r.NameEN) {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// Contains reports whether the province with the given code is in r.
+func (r *Region) Contains(provinceCode string) bool {
+	provinceCode = strings.TrimSpace(provinceCode)
+	for _, code := range r.Provinces {
+		if code == provinceCode {
+			return true
+		}
+	}
+	return false
+}
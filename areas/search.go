@@ -0,0 +1,177 @@
+package areas
+
+import (
+	"sort"
+	"strings"
+)
+
+type Candidate struct {
+	Name  string
+	Level Level
+	Count int
+}
+
+type Match struct {
+	Name     string `json:"name"`
+	Level    Level  `json:"level"`
+	Code     string `json:"code,omitempty"`
+	NameEN   string `json:"name_en,omitempty"`
+	Count    int    `json:"count"`
+	Kind     string `json:"match"`
+	Distance int    `json:"distance"`
+}
+
+const (
+	matchExact  = "exact"
+	matchPrefix = "prefix"
+	matchFuzzy  = "fuzzy"
+)
+
+// Search ranks candidates against query: exact key matches first, then
+// prefix matches, then names within a small edit distance. Results with the
+// same rank are ordered by item count so busy areas surface first.
+func (g *Gazetteer) Search(query string, candidates []Candidate, limit int) []Match {
+	q := Key(query)
+	if q == "" {
+		return nil
+	}
+	maxDist := maxDistance(q)
+
+	seen := make(map[string]int)
+	matches := make([]Match, 0)
+	for _, cand := range candidates {
+		key := Key(cand.Name)
+		if key == "" {
+			continue
+		}
+
+		keys := []string{key}
+		dedupe := string(cand.Level) + "|" + key
+		area, resolved := g.Resolve(cand.Level, cand.Name)
+		if resolved {
+			dedupe = string(cand.Level) + "#" + area.Code
+			for _, name := range area.names() {
+				keys = append(keys, Key(name))
+			}
+		}
+		if idx, ok := seen[dedupe]; ok {
+			matches[idx].Count += cand.Count
+			continue
+		}
+
+		m, ok := bestMatch(q, keys, maxDist)
+		if !ok {
+			continue
+		}
+
+		m.Name = cand.Name
+		m.Level = cand.Level
+		m.Count = cand.Count
+		if resolved {
+			m.Name = area.NameTH
+			m.Code = area.Code
+			m.NameEN = area.NameEN
+		}
+		seen[dedupe] = len(matches)
+		matches = append(matches, m)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		ri, rj := matchRank(matches[i].Kind), matchRank(matches[j].Kind)
+		if ri != rj {
+			return ri < rj
+		}
+		if matches[i].Kind == matchFuzzy && matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].Count > matches[j].Count
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// Suggest returns up to limit names that are close to query, for use as
+// "did you mean" hints when a lookup finds nothing.
+func (g *Gazetteer) Suggest(query string, candidates []Candidate, limit int) []string {
+	matches := g.Search(query, candidates, limit)
+	names := make([]string, 0, len(matches))
+	for _, m := range matches {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+func bestMatch(q string, keys []string, maxDist int) (Match, bool) {
+	best, found := Match{}, false
+	for _, key := range keys {
+		var m Match
+		switch {
+		case key == "":
+			continue
+		case key == q:
+			m = Match{Kind: matchExact}
+		case strings.HasPrefix(key, q):
+			m = Match{Kind: matchPrefix, Distance: runeLen(key) - runeLen(q)}
+		default:
+			d := levenshtein(q, key)
+			if d > maxDist {
+				continue
+			}
+			m = Match{Kind: matchFuzzy, Distance: d}
+		}
+		if !found || matchRank(m.Kind) < matchRank(best.Kind) ||
+			(m.Kind == best.Kind && m.Distance < best.Distance) {
+			best, found = m, true
+		}
+	}
+	return best, found
+}
+
+func matchRank(kind string) int {
+	switch kind {
+	case matchExact:
+		return 0
+	case matchPrefix:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Thai names are short in runes but typos often hit combining marks, so
+// allow roughly one edit per three runes with a floor of one.
+func maxDistance(key string) int {
+	d := runeLen(key) / 3
+	if d < 1 {
+		d = 1
+	}
+	return d
+}
+
+func runeLen(s string) int {
+	return len([]rune(s))
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,230 @@
+package category
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Nxdus/hatyai-api/filewatch"
+	"github.com/Nxdus/hatyai-api/textnorm"
+)
+
+// Category is a canonical kind of help a request asks for.
+type Category string
+
+// Unknown is given to requests that match no category.
+const Unknown Category = "unknown"
+
+//go:embed categories.json
+var defaultTaxonomyJSON []byte
+
+// Taxonomy maps upstream type_name values and keywords in the request text
+// to categories. A keyword directly preceded by a negation ("ไม่ต้องการ
+// อาหาร") does not count.
+type Taxonomy struct {
+	Version    string       `json:"version"`
+	Negations  []string     `json:"negations"`
+	Categories []Definition `json:"categories"`
+
+	byType    map[string]Category
+	negations []string
+}
+
+type Definition struct {
+	Category  Category `json:"category"`
+	Label     string   `json:"label"`
+	LabelEN   string   `json:"label_en"`
+	TypeNames []string `json:"type_names"`
+	Keywords  []string `json:"keywords"`
+
+	keywords []string
+}
+
+var activeTaxonomy atomic.Pointer[Taxonomy]
+
+func init() {
+	t, err := ParseTaxonomy(defaultTaxonomyJSON)
+	if err != nil {
+		panic("category: invalid embedded taxonomy: " + err.Error())
+	}
+	activeTaxonomy.Store(t)
+}
+
+// ActiveTaxonomy returns the taxonomy currently loaded.
+func ActiveTaxonomy() *Taxonomy {
+	return activeTaxonomy.Load()
+}
+
+// Classify returns the categories of a request using the active taxonomy.
+func Classify(typeName, text string) []Category {
+	return ActiveTaxonomy().Classify(typeName, text)
+}
+
+// Classify returns the categories of a request. The category of its
+// type_name comes first: an exact type_names entry, or else the first
+// category with a keyword in type_name. Every other category with a keyword
+// in text follows, in file order. A request matching nothing is Unknown.
+func (t *Taxonomy) Classify(typeName, text string) []Category {
+	var found []Category
+	add := func(c Category) {
+		for _, f := range found {
+			if f == c {
+				return
+			}
+		}
+		found = append(found, c)
+	}
+
+	typeKey := textnorm.Compact(typeName)
+	if c, ok := t.byType[typeKey]; ok {
+		add(c)
+	} else if typeKey != "" {
+		if d := t.firstMatch(typeKey); d != nil {
+			add(d.Category)
+		}
+	}
+
+	textKey := textnorm.Compact(text)
+	if textKey != "" {
+		for i := range t.Categories {
+			if t.matches(&t.Categories[i], textKey) {
+				add(t.Categories[i].Category)
+			}
+		}
+	}
+
+	if len(found) == 0 {
+		return []Category{Unknown}
+	}
+	return found
+}
+
+// Names lists the categories in file order, followed by Unknown.
+func (t *Taxonomy) Names() []Category {
+	names := make([]Category, 0, len(t.Categories)+1)
+	for _, d := range t.Categories {
+		names = append(names, d.Category)
+	}
+	return append(names, Unknown)
+}
+
+// ParseCategories reads a comma-separated list of categories into a set.
+func (t *Taxonomy) ParseCategories(val string) (map[Category]bool, error) {
+	set := make(map[Category]bool)
+	for _, part := range strings.Split(val, ",") {
+		name := Category(strings.ToLower(strings.TrimSpace(part)))
+		if name == "" {
+			continue
+		}
+		if !t.has(name) {
+			return nil, fmt.Errorf("unknown category %q", name)
+		}
+		set[name] = true
+	}
+	return set, nil
+}
+
+func (t *Taxonomy) has(c Category) bool {
+	for _, name := range t.Names() {
+		if name == c {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Taxonomy) firstMatch(key string) *Definition {
+	for i := range t.Categories {
+		if t.matches(&t.Categories[i], key) {
+			return &t.Categories[i]
+		}
+	}
+	return nil
+}
+
+func (t *Taxonomy) matches(d *Definition, key string) bool {
+	for _, kw := range d.keywords {
+		if textnorm.ContainsUnnegated(key, kw, t.negations) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTaxonomy decodes and validates a taxonomy file.
+func ParseTaxonomy(data []byte) (*Taxonomy, error) {
+	var t Taxonomy
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&t); err != nil {
+		return nil, err
+	}
+	if t.Version == "" {
+		return nil, errors.New("category: version is required")
+	}
+
+	for _, neg := range t.Negations {
+		if key := textnorm.Compact(neg); key != "" {
+			t.negations = append(t.negations, key)
+		}
+	}
+	t.byType = make(map[string]Category)
+	seen := make(map[Category]bool, len(t.Categories))
+	for i := range t.Categories {
+		d := &t.Categories[i]
+		if d.Category == "" || d.Category == Unknown {
+			return nil, fmt.Errorf("category: categories[%d] needs a category other than %q", i, Unknown)
+		}
+		if d.Category != Category(strings.ToLower(string(d.Category))) {
+			return nil, fmt.Errorf("category: categories[%d] category %q must be lower case", i, d.Category)
+		}
+		if seen[d.Category] {
+			return nil, fmt.Errorf("category: %q is defined twice", d.Category)
+		}
+		seen[d.Category] = true
+
+		for _, name := range d.TypeNames {
+			key := textnorm.Compact(name)
+			if prev, ok := t.byType[key]; ok && prev != d.Category {
+				return nil, fmt.Errorf("category: type_name %q is listed under both %s and %s", name, prev, d.Category)
+			}
+			t.byType[key] = d.Category
+		}
+		for _, kw := range d.Keywords {
+			if key := textnorm.Compact(kw); key != "" {
+				d.keywords = append(d.keywords, key)
+			}
+		}
+	}
+	return &t, nil
+}
+
+func LoadTaxonomy(path string) (*Taxonomy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTaxonomy(data)
+}
+
+// WatchTaxonomy loads the taxonomy from path and polls it for changes. A
+// file that fails to load or validate is logged and the previous taxonomy
+// stays active.
+func WatchTaxonomy(path string, interval time.Duration) error {
+	return filewatch.Watch(path, interval, func() error {
+		t, err := LoadTaxonomy(path)
+		if err != nil {
+			return err
+		}
+		activeTaxonomy.Store(t)
+		log.Printf("category taxonomy loaded (version=%s, file=%s)", t.Version, path)
+		return nil
+	})
+}
@@ -0,0 +1,56 @@
+package category
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name           string
+		typeName, text string
+		want           []Category
+	}{
+		{"exact type_name", "ผู้ป่วยฉุกเฉิน", "", []Category{"medical"}},
+		{"keyword in type_name", "ต้องการอาหารด่วน", "", []Category{"food"}},
+		{"keyword in other", "", "ขอ ถุง ยัง ชีพ ด้วยครับ", []Category{"supplies"}},
+		{"type_name first, other after in file order", "อาหาร", "มีผู้ป่วยติดเตียง ต้องการเรือ", []Category{"food", "medical", "evacuation"}},
+		{"type_name category not repeated", "อพยพ", "ติดอยู่บนหลังคา", []Category{"evacuation"}},
+		{"English", "", "Trapped on the roof, need drinking water", []Category{"evacuation", "food"}},
+		{"negated keyword", "", "ไม่ต้องการอาหาร ขอเรืออพยพ", []Category{"evacuation"}},
+		{"negation with spaces", "", "ไม่ ต้องการ อาหาร", []Category{Unknown}},
+		{"English negation", "", "we don't need food, trapped", []Category{"evacuation"}},
+		{"negation applies to the next keyword only", "", "ไม่ต้องการอาหาร แต่ขาดน้ำดื่ม", []Category{"food"}},
+		{"lack is not a negation", "", "ไม่มีอาหาร", []Category{"food"}},
+		{"nothing matches", "อื่นๆ", "สอบถามข้อมูล", []Category{Unknown}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.typeName, tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Classify(%q, %q) = %v, want %v", tt.typeName, tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTaxonomyRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"no version", `{"categories": []}`},
+		{"unknown field", `{"version": "1", "categories": [], "extra": 1}`},
+		{"upper case category", `{"version": "1", "categories": [{"category": "Food"}]}`},
+		{"unknown as category", `{"version": "1", "categories": [{"category": "unknown"}]}`},
+		{"defined twice", `{"version": "1", "categories": [{"category": "food"}, {"category": "food"}]}`},
+		{"type_name in two categories", `{"version": "1", "categories": [
+			{"category": "food", "type_names": ["x"]},
+			{"category": "supplies", "type_names": ["X"]}
+		]}`},
+	}
+	for _, tt := range tests {
+		if _, err := ParseTaxonomy([]byte(tt.json)); err == nil {
+			t.Errorf("%s: ParseTaxonomy accepted %s", tt.name, tt.json)
+		}
+	}
+}
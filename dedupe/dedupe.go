@@ -0,0 +1,348 @@
+package dedupe
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Nxdus/hatyai-api/services"
+	"github.com/Nxdus/hatyai-api/textnorm"
+)
+
+const (
+	// Requests further apart than this are only linked by a shared phone
+	// number.
+	maxDistanceMeters = 100
+	// A shared phone number links requests up to this far apart whose text
+	// is at least minSimilarity alike, or at any distance when it is at
+	// least maxSimilarity alike. One caller often reports several
+	// households, so a phone alone is not enough.
+	phoneMaxDistanceMeters = 200
+	// A phone number mentioned by more requests than this is taken to be a
+	// hotline or volunteer number and is not compared at all.
+	maxPhoneShare = 20
+	// Requests created further apart than this are never linked.
+	maxTimeGap = 72 * time.Hour
+	// Text similarity needed at 0 m; it rises linearly to maxSimilarity at
+	// maxDistanceMeters.
+	minSimilarity = 0.25
+	maxSimilarity = 0.5
+
+	gridDegrees = 0.001 // about 110 m of latitude
+)
+
+// Member is a request found to duplicate its group's representative, with
+// the evidence compared against the representative.
+type Member struct {
+	ID             string   `json:"_id"`
+	RunningNumber  string   `json:"running_number"`
+	DistanceMeters *float64 `json:"distance_m,omitempty"`
+	TextSimilarity float64  `json:"text_similarity"`
+	SharedPhone    bool     `json:"shared_phone"`
+}
+
+// Group is a set of requests that look like repeat reports of the same
+// need. The representative is the member that comes first in the input.
+type Group struct {
+	Representative string   `json:"representative"`
+	IDs            []string `json:"ids"`
+	RunningNumbers []string `json:"running_numbers"`
+	Members        []Member `json:"members"`
+
+	indexes []int
+	// direct are the indexes of members linked to the representative
+	// itself rather than only through other members.
+	direct []int
+}
+
+type features struct {
+	lat, lon  float64
+	located   bool
+	phones    map[string]struct{}
+	trigrams  map[string]struct{}
+	created   time.Time
+	hasCreate bool
+}
+
+// Detect groups the items that duplicate each other. Two requests are
+// linked when they were created within 72 hours of each other and either
+// are within 100 m with similar text, the closer the less alike, or mention
+// the same phone number and have alike text, somewhat alike within 200 m.
+// Links are transitive. Only groups of two or more are returned, in input
+// order.
+func Detect(items []services.DataItem) []Group {
+	feats := make([]features, len(items))
+	grid := make(map[[2]int][]int)
+	byPhone := make(map[string][]int)
+	for i, item := range items {
+		f := extract(item)
+		feats[i] = f
+		if f.located {
+			cell := gridCell(f.lat, f.lon)
+			grid[cell] = append(grid[cell], i)
+		}
+		for p := range f.phones {
+			byPhone[p] = append(byPhone[p], i)
+		}
+	}
+
+	parent := make([]int, len(items))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(a, b int) {
+		ra, rb := find(a), find(b)
+		if ra == rb {
+			return
+		}
+		// The root is the earliest item so it becomes the representative.
+		if rb < ra {
+			ra, rb = rb, ra
+		}
+		parent[rb] = ra
+	}
+
+	for _, list := range byPhone {
+		if len(list) > maxPhoneShare {
+			continue
+		}
+		for a := 0; a < len(list); a++ {
+			for b := a + 1; b < len(list); b++ {
+				if linked(feats[list[a]], feats[list[b]]) {
+					union(list[a], list[b])
+				}
+			}
+		}
+	}
+	for i := range items {
+		if !feats[i].located {
+			continue
+		}
+		cell := gridCell(feats[i].lat, feats[i].lon)
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				for _, j := range grid[[2]int{cell[0] + dx, cell[1] + dy}] {
+					if j > i && linked(feats[i], feats[j]) {
+						union(i, j)
+					}
+				}
+			}
+		}
+	}
+
+	byRoot := make(map[int]*Group)
+	order := make([]int, 0)
+	for i, item := range items {
+		root := find(i)
+		g, ok := byRoot[root]
+		if !ok {
+			g = &Group{Representative: items[root].ID}
+			byRoot[root] = g
+			order = append(order, root)
+		}
+		g.indexes = append(g.indexes, i)
+		g.IDs = append(g.IDs, item.ID)
+		g.RunningNumbers = append(g.RunningNumbers, runningNumber(item))
+		if i != root {
+			rep := feats[root]
+			if linked(rep, feats[i]) {
+				g.direct = append(g.direct, i)
+			}
+			g.Members = append(g.Members, Member{
+				ID:             item.ID,
+				RunningNumber:  runningNumber(item),
+				DistanceMeters: meters(distance(rep, feats[i])),
+				TextSimilarity: math.Round(similarity(rep.trigrams, feats[i].trigrams)*100) / 100,
+				SharedPhone:    sharesPhone(rep, feats[i]),
+			})
+		}
+	}
+
+	groups := make([]Group, 0)
+	for _, root := range order {
+		if g := byRoot[root]; len(g.indexes) > 1 {
+			if g.Members == nil {
+				g.Members = []Member{}
+			}
+			groups = append(groups, *g)
+		}
+	}
+	return groups
+}
+
+// Collapse keeps one representative per duplicate group, in input order,
+// and returns the ids of the requests each representative stands for. Only
+// members linked to the representative itself are hidden; one reached only
+// through a chain of other members stays in the list.
+func Collapse(items []services.DataItem) ([]services.DataItem, map[string][]string) {
+	groups := Detect(items)
+	drop := make(map[int]struct{})
+	linked := make(map[string][]string, len(groups))
+	for _, g := range groups {
+		if len(g.direct) == 0 {
+			continue
+		}
+		ids := make([]string, 0, len(g.direct))
+		for _, idx := range g.direct {
+			drop[idx] = struct{}{}
+			ids = append(ids, items[idx].ID)
+		}
+		linked[g.Representative] = ids
+	}
+
+	kept := make([]services.DataItem, 0, len(items)-len(drop))
+	for i, item := range items {
+		if _, ok := drop[i]; !ok {
+			kept = append(kept, item)
+		}
+	}
+	return kept, linked
+}
+
+func linked(a, b features) bool {
+	if !withinTime(a, b) {
+		return false
+	}
+	d := distance(a, b)
+	sim := similarity(a.trigrams, b.trigrams)
+	if sharesPhone(a, b) && (sim >= maxSimilarity || d <= phoneMaxDistanceMeters && sim >= minSimilarity) {
+		return true
+	}
+	if d > maxDistanceMeters {
+		return false
+	}
+	need := minSimilarity + (maxSimilarity-minSimilarity)*d/maxDistanceMeters
+	return sim >= need
+}
+
+func withinTime(a, b features) bool {
+	if !a.hasCreate || !b.hasCreate {
+		return true
+	}
+	gap := a.created.Sub(b.created)
+	if gap < 0 {
+		gap = -gap
+	}
+	return gap <= maxTimeGap
+}
+
+func sharesPhone(a, b features) bool {
+	for p := range a.phones {
+		if _, ok := b.phones[p]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func extract(item services.DataItem) features {
+	prop := item.Location.Properties
+	f := features{}
+	if coords := item.Location.Geometry.Coordinates; len(coords) >= 2 && (coords[0] != 0 || coords[1] != 0) {
+		f.lon, f.lat, f.located = coords[0], coords[1], true
+	}
+	text := prop.Other + " " + prop.Disease
+	f.phones = phones(text)
+	f.trigrams = trigrams(text)
+	if t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(item.CreatedAt)); err == nil {
+		f.created, f.hasCreate = t, true
+	}
+	return f
+}
+
+var phonePattern = regexp.MustCompile(`(?:\+66|\b66|\b0)(?:[\s.-]?\d){8,9}\b`)
+
+// phones finds Thai phone numbers in text and normalises them to the
+// 0XXXXXXXX(X) form, whatever separators or +66 prefix were typed.
+func phones(text string) map[string]struct{} {
+	found := make(map[string]struct{})
+	for _, m := range phonePattern.FindAllString(textnorm.Normalize(text), -1) {
+		digits := strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, m)
+		if strings.HasPrefix(digits, "66") {
+			digits = "0" + digits[2:]
+		}
+		if len(digits) == 9 || len(digits) == 10 {
+			found[digits] = struct{}{}
+		}
+	}
+	return found
+}
+
+// trigrams returns the character trigrams of the compacted text with phone
+// numbers and digits removed, so that retyped requests compare as similar.
+func trigrams(text string) map[string]struct{} {
+	runes := make([]rune, 0, len(text))
+	for _, r := range textnorm.Compact(phonePattern.ReplaceAllString(textnorm.Normalize(text), " ")) {
+		if r == ' ' || (r >= '0' && r <= '9') {
+			continue
+		}
+		runes = append(runes, r)
+	}
+	set := make(map[string]struct{})
+	for i := 0; i+3 <= len(runes); i++ {
+		set[string(runes[i:i+3])] = struct{}{}
+	}
+	return set
+}
+
+// similarity is the Jaccard index of two trigram sets; empty text is never
+// similar to anything.
+func similarity(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range a {
+		if _, ok := b[t]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+func gridCell(lat, lon float64) [2]int {
+	return [2]int{int(math.Floor(lat / gridDegrees)), int(math.Floor(lon / gridDegrees))}
+}
+
+// distance is the haversine distance in metres, or +Inf when either request
+// has no location.
+func distance(a, b features) float64 {
+	if !a.located || !b.located {
+		return math.Inf(1)
+	}
+	const earthRadius = 6371000
+	lat1, lat2 := a.lat*math.Pi/180, b.lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.lon - a.lon) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
+
+// meters rounds a distance for output, leaving it out when unknown.
+func meters(d float64) *float64 {
+	if math.IsInf(d, 0) {
+		return nil
+	}
+	d = math.Round(d)
+	return &d
+}
+
+func runningNumber(item services.DataItem) string {
+	if item.RunningNumber != "" {
+		return item.RunningNumber
+	}
+	return item.Location.Properties.RunningNumber
+}
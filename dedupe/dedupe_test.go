@@ -0,0 +1,97 @@
+package dedupe
+
+import (
+	"testing"
+
+	"github.com/Nxdus/hatyai-api/services"
+)
+
+func item(id string, lat, lon float64, text string) services.DataItem {
+	var it services.DataItem
+	it.ID = id
+	it.CreatedAt = "2025-11-28T10:00:00Z"
+	it.Location.Geometry.Coordinates = []float64{lon, lat}
+	it.Location.Properties.Other = text
+	return it
+}
+
+func TestDetectPhone(t *testing.T) {
+	const phone = "โทร 081-234-5678"
+	tests := []struct {
+		name string
+		a, b services.DataItem
+		want bool
+	}{
+		{
+			"one phone on two households 300 m apart",
+			item("a", 7.0000, 100.4700, "น้ำท่วมชั้นล่างต้องการเรือ "+phone),
+			item("b", 7.0027, 100.4700, "น้ำท่วมชั้นล่างขาดน้ำดื่ม "+phone),
+			false,
+		},
+		{
+			"same phone nearby with unrelated text",
+			item("a", 7.0000, 100.4700, "บ้านน้ำท่วม "+phone),
+			item("b", 7.00135, 100.4700, "ต้องการเรือ "+phone),
+			false,
+		},
+		{
+			"same phone nearby with some shared text",
+			item("a", 7.0000, 100.4700, "น้ำท่วมชั้นล่างต้องการเรือ "+phone),
+			item("b", 7.00135, 100.4700, "น้ำท่วมชั้นล่างขาดน้ำดื่ม "+phone),
+			true,
+		},
+		{
+			"same phone far apart",
+			item("a", 7.0000, 100.4700, "บ้านน้ำท่วม "+phone),
+			item("b", 7.1000, 100.4700, "ต้องการเรือ "+phone),
+			false,
+		},
+		{
+			"same phone far apart with alike text",
+			item("a", 7.0000, 100.4700, "ผู้สูงอายุติดอยู่ชั้นสองต้องการเรือ "+phone),
+			item("b", 7.1000, 100.4700, "ผู้สูงอายุติดอยู่ชั้นสองต้องการเรือด่วน "+phone),
+			true,
+		},
+	}
+	for _, tt := range tests {
+		got := len(Detect([]services.DataItem{tt.a, tt.b})) == 1
+		if got != tt.want {
+			t.Errorf("%s: linked = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDetectSkipsHotlineNumbers(t *testing.T) {
+	const hotline = "โทร 081-234-5678"
+	items := make([]services.DataItem, 0, maxPhoneShare+1)
+	for i := 0; i <= maxPhoneShare; i++ {
+		items = append(items, item(string(rune('a'+i)), 7.0+0.1*float64(i), 100.4700, "ผู้สูงอายุติดอยู่ชั้นสองต้องการเรือ "+hotline))
+	}
+	if groups := Detect(items); len(groups) != 0 {
+		t.Errorf("Detect = %+v, want no groups", groups)
+	}
+}
+
+func TestCollapseKeepsChainedMembers(t *testing.T) {
+	const phone = "โทร 081-234-5678"
+	items := []services.DataItem{
+		item("a", 7.0000, 100.4700, "น้ำท่วมชั้นล่างต้องการเรือ "+phone),
+		item("b", 7.00135, 100.4700, "น้ำท่วมชั้นล่างขาดน้ำดื่ม "+phone),
+		item("c", 7.0027, 100.4700, "น้ำท่วมบ้านขาดน้ำดื่ม "+phone),
+	}
+	if groups := Detect(items); len(groups) != 1 || len(groups[0].IDs) != 3 {
+		t.Fatalf("Detect = %+v, want one group of three", groups)
+	}
+
+	kept, linked := Collapse(items)
+	var ids []string
+	for _, it := range kept {
+		ids = append(ids, it.ID)
+	}
+	if len(ids) != 2 || ids[0] != "a" || ids[1] != "c" {
+		t.Errorf("kept = %v, want [a c]", ids)
+	}
+	if got := linked["a"]; len(got) != 1 || got[0] != "b" {
+		t.Errorf("linked[a] = %v, want [b]", got)
+	}
+}
@@ -0,0 +1,43 @@
+// Package filewatch reloads configuration files when they change on disk.
+package filewatch
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// Watch calls load once and then again whenever the modification time of
+// path changes, checking every interval. An error from the first load is
+// returned and nothing is watched. A later load that fails is logged, and
+// whatever the previous load set up is expected to stay in place.
+func Watch(path string, interval time.Duration, load func() error) error {
+	if err := load(); err != nil {
+		return err
+	}
+
+	lastMod := modTime(path)
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			mod := modTime(path)
+			if mod.IsZero() || mod.Equal(lastMod) {
+				continue
+			}
+			lastMod = mod
+
+			if err := load(); err != nil {
+				log.Printf("reload of %s failed, keeping the previous version: %v", path, err)
+			}
+		}
+	}()
+	return nil
+}
+
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,47 @@
+package filewatch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var loads atomic.Int32
+	if err := Watch(path, 5*time.Millisecond, func() error {
+		loads.Add(1)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("loads after Watch = %d, want 1", n)
+	}
+
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for loads.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := loads.Load(); n != 2 {
+		t.Errorf("loads after change = %d, want 2", n)
+	}
+}
+
+func TestWatchFirstLoadFails(t *testing.T) {
+	want := errors.New("invalid")
+	if err := Watch("missing.json", time.Hour, func() error { return want }); err != want {
+		t.Errorf("Watch = %v, want %v", err, want)
+	}
+}
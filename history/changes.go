@@ -0,0 +1,230 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Nxdus/hatyai-api/services"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisKeyState      = "request:state"
+	redisKeyStateAt    = "request:state:at"
+	redisKeyItemPrefix = "request:history:"
+	maxEvents          = 200
+	eventRetention     = 30 * 24 * time.Hour
+	maxRecordAttempts  = 3
+	itemFieldPrefix    = "item."
+)
+
+const (
+	EventAdded   = "added"
+	EventChanged = "changed"
+	EventRemoved = "removed"
+)
+
+// Change is one field that differs between two snapshots. Field is the path
+// in the item, with properties named directly ("status_text"), top-level
+// fields prefixed with "item." ("item.updated_at", which would otherwise
+// collide with the property of the same name) and other fields by their
+// full path ("location.geometry.coordinates").
+type Change struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old"`
+	New   json.RawMessage `json:"new"`
+}
+
+// Event is what happened to an item in one snapshot: it appeared in the
+// feed, some of its fields changed, or it left the feed.
+type Event struct {
+	At      time.Time `json:"at"`
+	Type    string    `json:"type"`
+	Changes []Change  `json:"changes,omitempty"`
+}
+
+// recordChanges compares every item with its state in the previous snapshot
+// and appends an event to the item's timeline for each difference. A
+// snapshot no newer than the last one recorded is skipped, so instances
+// that fetch the same snapshot do not record it twice. The state is read
+// under WATCH, so when two instances record at once one of them retries
+// against the other's result.
+func (s *Store) recordChanges(ctx context.Context, data *services.APIResponse, at time.Time) error {
+	var err error
+	for i := 0; i < maxRecordAttempts; i++ {
+		err = s.redis.Watch(ctx, func(tx *redis.Tx) error {
+			return recordChangesTx(ctx, tx, data, at)
+		}, redisKeyStateAt)
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+	return err
+}
+
+func recordChangesTx(ctx context.Context, tx *redis.Tx, data *services.APIResponse, at time.Time) error {
+	last, err := tx.Get(ctx, redisKeyStateAt).Int64()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	if last >= at.Unix() {
+		return nil
+	}
+
+	stored, err := tx.HGetAll(ctx, redisKeyState).Result()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, redisKeyStateAt, at.Unix(), 0)
+		push := func(id string, ev Event) {
+			b, err := json.Marshal(ev)
+			if err != nil {
+				return
+			}
+			key := redisKeyItemPrefix + id
+			pipe.RPush(ctx, key, b)
+			pipe.LTrim(ctx, key, -maxEvents, -1)
+			pipe.Expire(ctx, key, eventRetention)
+		}
+
+		states := make(map[string]interface{})
+		seen := make(map[string]struct{}, len(data.Data.Data))
+		for _, item := range data.Data.Data {
+			if item.ID == "" {
+				continue
+			}
+			seen[item.ID] = struct{}{}
+			fields, err := flatten(item)
+			if err != nil {
+				continue
+			}
+			b, err := json.Marshal(fields)
+			if err != nil {
+				continue
+			}
+
+			raw, ok := stored[item.ID]
+			if !ok {
+				push(item.ID, Event{At: at, Type: EventAdded})
+				states[item.ID] = b
+				continue
+			}
+			if raw == string(b) {
+				continue
+			}
+			var prev map[string]json.RawMessage
+			if json.Unmarshal([]byte(raw), &prev) != nil {
+				prev = nil
+			}
+			if changes := diff(prev, fields); len(changes) > 0 {
+				push(item.ID, Event{At: at, Type: EventChanged, Changes: changes})
+			}
+			states[item.ID] = b
+		}
+
+		var gone []string
+		for id := range stored {
+			if _, ok := seen[id]; !ok {
+				push(id, Event{At: at, Type: EventRemoved})
+				gone = append(gone, id)
+			}
+		}
+		if len(states) > 0 {
+			pipe.HSet(ctx, redisKeyState, states)
+		}
+		if len(gone) > 0 {
+			pipe.HDel(ctx, redisKeyState, gone...)
+		}
+		return nil
+	})
+	return err
+}
+
+// Events returns an item's timeline, oldest first.
+func (s *Store) Events(id string) ([]Event, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	vals, err := s.redis.LRange(ctx, redisKeyItemPrefix+id, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	events := make([]Event, 0, len(vals))
+	for _, v := range vals {
+		var ev Event
+		if json.Unmarshal([]byte(v), &ev) == nil {
+			events = append(events, ev)
+		}
+	}
+	return events, nil
+}
+
+// flatten turns an item into a map from field path to JSON value. Objects
+// are walked into; arrays such as victims and coordinates are kept whole.
+func flatten(item services.DataItem) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]json.RawMessage)
+	var walk func(prefix string, raw json.RawMessage)
+	walk = func(prefix string, raw json.RawMessage) {
+		var obj map[string]json.RawMessage
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) && json.Unmarshal(raw, &obj) == nil {
+			for k, v := range obj {
+				walk(prefix+k+".", v)
+			}
+			return
+		}
+		fields[fieldName(strings.TrimSuffix(prefix, "."))] = raw
+	}
+	walk("", b)
+	return fields, nil
+}
+
+func fieldName(path string) string {
+	if name, ok := strings.CutPrefix(path, "location.properties."); ok {
+		return name
+	}
+	if !strings.Contains(path, ".") {
+		return itemFieldPrefix + path
+	}
+	return path
+}
+
+// diff lists the fields whose value differs, in field order. A field missing
+// on one side has a null value there.
+func diff(old, new map[string]json.RawMessage) []Change {
+	names := make(map[string]struct{}, len(new))
+	for k := range old {
+		names[k] = struct{}{}
+	}
+	for k := range new {
+		names[k] = struct{}{}
+	}
+
+	changes := make([]Change, 0)
+	for k := range names {
+		o, n := valueOrNull(old[k]), valueOrNull(new[k])
+		if !bytes.Equal(o, n) {
+			changes = append(changes, Change{Field: k, Old: o, New: n})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
+
+func valueOrNull(v json.RawMessage) json.RawMessage {
+	if len(v) == 0 {
+		return json.RawMessage("null")
+	}
+	return v
+}
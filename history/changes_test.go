@@ -0,0 +1,52 @@
+package history
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Nxdus/hatyai-api/services"
+)
+
+func TestFlatten(t *testing.T) {
+	var item services.DataItem
+	err := json.Unmarshal([]byte(`{
+		"_id": "a1",
+		"running_number": "R-1",
+		"updated_at": "2025-11-28T10:00:00Z",
+		"lifecycle": "upstream",
+		"location": {
+			"type": "Feature",
+			"properties": {"running_number": "P-1", "updated_at": "2025-11-28T09:00:00Z", "status_text": "รอรับเรื่อง"},
+			"geometry": {"type": "Point", "coordinates": [100.4, 7.0]}
+		}
+	}`), &item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := flatten(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"item._id", `"a1"`},
+		{"item.running_number", `"R-1"`},
+		{"item.updated_at", `"2025-11-28T10:00:00Z"`},
+		{"running_number", `"P-1"`},
+		{"updated_at", `"2025-11-28T09:00:00Z"`},
+		{"status_text", `"รอรับเรื่อง"`},
+		{"item.lifecycle", `"upstream"`},
+		{"location.geometry.coordinates", `[100.4,7]`},
+	}
+	for _, tt := range tests {
+		if got := string(fields[tt.field]); got != tt.want {
+			t.Errorf("fields[%q] = %s, want %s", tt.field, got, tt.want)
+		}
+	}
+	if _, ok := fields["item.categories"]; ok {
+		t.Error("fields has the derived categories")
+	}
+}
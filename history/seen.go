@@ -0,0 +1,149 @@
+package history
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Nxdus/hatyai-api/services"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisKeySeen        = "request:seen"
+	redisKeySeenRunning = "request:seen:running"
+	redisKeySeenAt      = "request:seen:at"
+	seenRetention       = 30 * 24 * time.Hour
+)
+
+// Seen records the last snapshot an item was in, so a lookup for an item
+// that has left the feed can say when it was last there.
+type Seen struct {
+	ID            string    `json:"_id"`
+	RunningNumber string    `json:"running_number"`
+	LastSeen      time.Time `json:"last_seen"`
+}
+
+// recordSeen stamps every item in the snapshot with the snapshot time and
+// forgets items not seen for 30 days, along with their status history. Besides the entries by _id it keeps
+// the _id last seen with each running number, and a sorted set of when
+// each _id was last seen so expiry does not read every entry.
+func (s *Store) recordSeen(ctx context.Context, data *services.APIResponse, at time.Time) error {
+	fields := make(map[string]interface{}, len(data.Data.Data))
+	running := make(map[string]interface{}, len(data.Data.Data))
+	stamps := make([]redis.Z, 0, len(data.Data.Data))
+	for _, item := range data.Data.Data {
+		if item.ID == "" {
+			continue
+		}
+		number := item.RunningNumber
+		if number == "" {
+			number = item.Location.Properties.RunningNumber
+		}
+		fields[item.ID] = strconv.FormatInt(at.Unix(), 10) + "|" + number
+		if number != "" {
+			running[number] = item.ID
+		}
+		stamps = append(stamps, redis.Z{Score: float64(at.Unix()), Member: item.ID})
+	}
+	if len(fields) > 0 {
+		pipe := s.redis.TxPipeline()
+		pipe.HSet(ctx, redisKeySeen, fields)
+		if len(running) > 0 {
+			pipe.HSet(ctx, redisKeySeenRunning, running)
+		}
+		pipe.ZAdd(ctx, redisKeySeenAt, stamps...)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+	}
+
+	cutoff := strconv.FormatInt(at.Add(-seenRetention).Unix(), 10)
+	expired, err := s.redis.ZRangeByScore(ctx, redisKeySeenAt, &redis.ZRangeBy{Min: "-inf", Max: "(" + cutoff}).Result()
+	if err != nil || len(expired) == 0 {
+		return err
+	}
+	raws, err := s.redis.HMGet(ctx, redisKeySeen, expired...).Result()
+	if err != nil {
+		return err
+	}
+	var numbers []string
+	for i, raw := range raws {
+		if raw, ok := raw.(string); ok {
+			if seen := parseSeen(expired[i], raw); seen.RunningNumber != "" {
+				numbers = append(numbers, seen.RunningNumber)
+			}
+		}
+	}
+	// A running number reused by a newer item keeps pointing at it.
+	var stale []string
+	if len(numbers) > 0 {
+		owners, err := s.redis.HMGet(ctx, redisKeySeenRunning, numbers...).Result()
+		if err != nil {
+			return err
+		}
+		gone := make(map[string]bool, len(expired))
+		for _, id := range expired {
+			gone[id] = true
+		}
+		for i, owner := range owners {
+			if id, ok := owner.(string); ok && gone[id] {
+				stale = append(stale, numbers[i])
+			}
+		}
+	}
+
+	members := make([]interface{}, len(expired))
+	for i, id := range expired {
+		members[i] = id
+	}
+	pipe := s.redis.TxPipeline()
+	pipe.HDel(ctx, redisKeySeen, expired...)
+	pipe.HDel(ctx, redisKeyStatus, expired...)
+	pipe.ZRem(ctx, redisKeySeenAt, members...)
+	if len(stale) > 0 {
+		pipe.HDel(ctx, redisKeySeenRunning, stale...)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// LastSeen returns when the item with the given _id was last in the feed.
+func (s *Store) LastSeen(id string) (*Seen, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	raw, err := s.redis.HGet(ctx, redisKeySeen, id).Result()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	seen := parseSeen(id, raw)
+	return &seen, true, nil
+}
+
+// LastSeenRunning is LastSeen by running number. A running number reused
+// by another request finds the one seen most recently.
+func (s *Store) LastSeenRunning(running string) (*Seen, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	id, err := s.redis.HGet(ctx, redisKeySeenRunning, running).Result()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return s.LastSeen(id)
+}
+
+// Entries are stored as "<unix time>|<running number>".
+func parseSeen(id, raw string) Seen {
+	at, running, _ := strings.Cut(raw, "|")
+	sec, _ := strconv.ParseInt(at, 10, 64)
+	return Seen{ID: id, RunningNumber: running, LastSeen: time.Unix(sec, 0).UTC()}
+}
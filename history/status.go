@@ -0,0 +1,192 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nxdus/hatyai-api/services"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisKeyStatus   = "request:status"
+	redisKeyStatusAt = "request:status:at"
+)
+
+// statusEntry is the last status_text seen for an item and when it was first
+// seen with it. Since is 0 when the item already had that status the first
+// time it was seen, in which case created_at is the best estimate.
+type statusEntry struct {
+	Status string `json:"status"`
+	Since  int64  `json:"since,omitempty"`
+}
+
+type Store struct {
+	redis *redis.Client
+
+	sinceCache struct {
+		mu    sync.Mutex
+		data  *services.APIResponse
+		since map[string]time.Time
+	}
+}
+
+func NewStore(redis *redis.Client) *Store {
+	return &Store{redis: redis}
+}
+
+// Record updates the history with a freshly fetched snapshot.
+func (s *Store) Record(data *services.APIResponse) {
+	if data == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	at := data.FetchedTime()
+	if err := s.recordStatus(ctx, data, at); err != nil {
+		log.Printf("failed to record status history key=%s: %v", redisKeyStatus, err)
+	}
+	if err := s.recordSeen(ctx, data, at); err != nil {
+		log.Printf("failed to record last seen key=%s: %v", redisKeySeen, err)
+	}
+	if err := s.recordChanges(ctx, data, at); err != nil {
+		log.Printf("failed to record item history key=%s: %v", redisKeyState, err)
+	}
+}
+
+// recordStatus compares the status_text of every item in a snapshot with
+// the last one seen and stamps the snapshot time on those that changed. A
+// snapshot no newer than the last one recorded is skipped, and the entries
+// are read under WATCH, so neither a second instance nor a late snapshot
+// can restamp a status and reset its waiting time. Items missing from a
+// snapshot keep their entry, since upstream may have sent a partial page;
+// recordSeen forgets them once they have been gone for 30 days.
+func (s *Store) recordStatus(ctx context.Context, data *services.APIResponse, snapshot time.Time) error {
+	var err error
+	for i := 0; i < maxRecordAttempts; i++ {
+		err = s.redis.Watch(ctx, func(tx *redis.Tx) error {
+			return recordStatusTx(ctx, tx, data, snapshot)
+		}, redisKeyStatusAt)
+		if err != redis.TxFailedErr {
+			break
+		}
+	}
+	if err == nil {
+		s.resetSinceCache()
+	}
+	return err
+}
+
+func recordStatusTx(ctx context.Context, tx *redis.Tx, data *services.APIResponse, snapshot time.Time) error {
+	at := snapshot.Unix()
+	last, err := tx.Get(ctx, redisKeyStatusAt).Int64()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	if last >= at {
+		return nil
+	}
+
+	stored, err := tx.HGetAll(ctx, redisKeyStatus).Result()
+	if err != nil {
+		return err
+	}
+
+	updates := make(map[string]interface{})
+	for _, item := range data.Data.Data {
+		if item.ID == "" {
+			continue
+		}
+		status := strings.TrimSpace(item.Location.Properties.StatusText)
+
+		entry := statusEntry{Status: status}
+		if raw, ok := stored[item.ID]; ok {
+			var prev statusEntry
+			if json.Unmarshal([]byte(raw), &prev) == nil && prev.Status == status {
+				continue
+			}
+			entry.Since = at
+		}
+		b, err := json.Marshal(entry)
+		if err != nil {
+			continue
+		}
+		updates[item.ID] = string(b)
+	}
+
+	_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, redisKeyStatusAt, at, 0)
+		if len(updates) > 0 {
+			pipe.HSet(ctx, redisKeyStatus, updates)
+		}
+		return nil
+	})
+	return err
+}
+
+// StatusSince returns, by item _id, when each item in data entered its
+// current status_text. Items whose status has not changed since they were
+// first seen are left out. The map is read once per snapshot and again
+// after the history records one.
+func (s *Store) StatusSince(data *services.APIResponse) (map[string]time.Time, error) {
+	c := &s.sinceCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.since != nil && c.data == data {
+		return c.since, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	stored, err := s.redis.HGetAll(ctx, redisKeyStatus).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	since := make(map[string]time.Time, len(stored))
+	for id, raw := range stored {
+		if t, ok := parseSince(raw); ok {
+			since[id] = t
+		}
+	}
+	c.data, c.since = data, since
+	return since, nil
+}
+
+// StatusSinceOf is StatusSince for the item with the given _id alone.
+func (s *Store) StatusSinceOf(id string) (time.Time, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	raw, err := s.redis.HGet(ctx, redisKeyStatus, id).Result()
+	if err == redis.Nil {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	t, ok := parseSince(raw)
+	return t, ok, nil
+}
+
+func (s *Store) resetSinceCache() {
+	c := &s.sinceCache
+	c.mu.Lock()
+	c.data, c.since = nil, nil
+	c.mu.Unlock()
+}
+
+func parseSince(raw string) (time.Time, bool) {
+	var entry statusEntry
+	if json.Unmarshal([]byte(raw), &entry) != nil || entry.Since == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(entry.Since, 0), true
+}
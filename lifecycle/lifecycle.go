@@ -0,0 +1,197 @@
+package lifecycle
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Nxdus/hatyai-api/filewatch"
+	"github.com/Nxdus/hatyai-api/textnorm"
+)
+
+// Stage is the canonical lifecycle of a request, classified from its free
+// text status_text.
+type Stage string
+
+const (
+	New          Stage = "new"
+	Acknowledged Stage = "acknowledged"
+	InProgress   Stage = "in_progress"
+	Rescued      Stage = "rescued"
+	Closed       Stage = "closed"
+	Unknown      Stage = "unknown"
+)
+
+// Stages lists every stage in lifecycle order.
+var Stages = []Stage{New, Acknowledged, InProgress, Rescued, Closed, Unknown}
+
+// Groups name sets of stages that filters accept in place of a stage. open
+// includes Unknown so that a request whose status cannot be read is never
+// hidden from the people working through open requests.
+var Groups = map[string][]Stage{
+	"open":     {New, Acknowledged, InProgress, Unknown},
+	"resolved": {Rescued, Closed},
+}
+
+//go:embed statuses.json
+var defaultMappingJSON []byte
+
+// Mapping classifies status_text values. A status listed exactly under a
+// stage gets that stage; otherwise the first stage, in file order, with a
+// keyword contained in the status wins. A keyword directly preceded by a
+// negation ("ไม่ปลอดภัยแล้ว") does not count.
+type Mapping struct {
+	Version   string         `json:"version"`
+	Negations []string       `json:"negations"`
+	Stages    []StageMapping `json:"stages"`
+
+	exact     map[string]Stage
+	negations []string
+}
+
+type StageMapping struct {
+	Stage    Stage    `json:"stage"`
+	Statuses []string `json:"statuses"`
+	Keywords []string `json:"keywords"`
+
+	keywords []string
+}
+
+var activeMapping atomic.Pointer[Mapping]
+
+func init() {
+	m, err := ParseMapping(defaultMappingJSON)
+	if err != nil {
+		panic("lifecycle: invalid embedded mapping: " + err.Error())
+	}
+	activeMapping.Store(m)
+}
+
+// ActiveMapping returns the mapping currently loaded.
+func ActiveMapping() *Mapping {
+	return activeMapping.Load()
+}
+
+// Classify returns the stage of status using the active mapping.
+func Classify(status string) Stage {
+	return ActiveMapping().Classify(status)
+}
+
+func (m *Mapping) Classify(status string) Stage {
+	key := textnorm.Compact(status)
+	if key == "" {
+		return Unknown
+	}
+	if stage, ok := m.exact[key]; ok {
+		return stage
+	}
+	for _, s := range m.Stages {
+		for _, kw := range s.keywords {
+			if textnorm.ContainsUnnegated(key, kw, m.negations) {
+				return s.Stage
+			}
+		}
+	}
+	return Unknown
+}
+
+// ParseStages reads a comma-separated list of stages and group names into
+// the set of stages it covers.
+func ParseStages(val string) (map[Stage]bool, error) {
+	set := make(map[Stage]bool)
+	for _, part := range strings.Split(val, ",") {
+		name := strings.ToLower(strings.TrimSpace(part))
+		if name == "" {
+			continue
+		}
+		if group, ok := Groups[name]; ok {
+			for _, s := range group {
+				set[s] = true
+			}
+			continue
+		}
+		if !valid(Stage(name)) {
+			return nil, fmt.Errorf("unknown lifecycle stage %q", name)
+		}
+		set[Stage(name)] = true
+	}
+	return set, nil
+}
+
+// ParseMapping decodes and validates a mapping file.
+func ParseMapping(data []byte) (*Mapping, error) {
+	var m Mapping
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	if m.Version == "" {
+		return nil, errors.New("lifecycle: version is required")
+	}
+
+	for _, neg := range m.Negations {
+		if key := textnorm.Compact(neg); key != "" {
+			m.negations = append(m.negations, key)
+		}
+	}
+	m.exact = make(map[string]Stage)
+	for i := range m.Stages {
+		s := &m.Stages[i]
+		if !valid(s.Stage) || s.Stage == Unknown {
+			return nil, fmt.Errorf("lifecycle: stages[%d] stage %q is not a lifecycle stage", i, s.Stage)
+		}
+		for _, status := range s.Statuses {
+			key := textnorm.Compact(status)
+			if prev, ok := m.exact[key]; ok && prev != s.Stage {
+				return nil, fmt.Errorf("lifecycle: status %q is listed under both %s and %s", status, prev, s.Stage)
+			}
+			m.exact[key] = s.Stage
+		}
+		for _, kw := range s.Keywords {
+			if key := textnorm.Compact(kw); key != "" {
+				s.keywords = append(s.keywords, key)
+			}
+		}
+	}
+	return &m, nil
+}
+
+func LoadMapping(path string) (*Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMapping(data)
+}
+
+// WatchMapping loads the mapping from path and polls it for changes. A file
+// that fails to load or validate is logged and the previous mapping stays
+// active.
+func WatchMapping(path string, interval time.Duration) error {
+	return filewatch.Watch(path, interval, func() error {
+		m, err := LoadMapping(path)
+		if err != nil {
+			return err
+		}
+		activeMapping.Store(m)
+		log.Printf("status mapping loaded (version=%s, file=%s)", m.Version, path)
+		return nil
+	})
+}
+
+func valid(s Stage) bool {
+	for _, stage := range Stages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package lifecycle
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	m := ActiveMapping()
+	tests := []struct {
+		status string
+		want   Stage
+	}{
+		{"", Unknown},
+		{"รอรับเรื่อง", New},
+		{"ยังไม่ได้รับการช่วยเหลือ", New},
+		{"ไม่ได้รับการช่วยเหลือ", New},
+		{"กำลังรอ", New},
+		{"กำลังดำเนินการ", InProgress},
+		{"ได้รับการช่วยเหลือแล้ว", Rescued},
+		{"ปลอดภัยแล้ว", Rescued},
+		{"ไม่ปลอดภัยแล้ว", Unknown},
+		{"ติดต่อไม่สำเร็จ", Unknown},
+		{"ช่วยเหลือไม่สำเร็จ", Unknown},
+		{"ช่วยเหลือสำเร็จ", Closed},
+		{"Rescued", Rescued},
+		{"not rescued", Unknown},
+	}
+	for _, tt := range tests {
+		if got := m.Classify(tt.status); got != tt.want {
+			t.Errorf("Classify(%q) = %s, want %s", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyNegationMustPrecede(t *testing.T) {
+	m, err := ParseMapping([]byte(`{
+		"version": "test",
+		"negations": ["ไม่", "no"],
+		"stages": [{"stage": "rescued", "statuses": [], "keywords": ["ปลอดภัย", "rescued"]}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		status string
+		want   Stage
+	}{
+		{"ปลอดภัย", Rescued},
+		{"ไม่ปลอดภัย", Unknown},
+		{"ไม่ปลอดภัย ตอนนี้ปลอดภัย", Rescued},
+		{"no rescued", Unknown},
+		{"casino rescued", Rescued},
+	}
+	for _, tt := range tests {
+		if got := m.Classify(tt.status); got != tt.want {
+			t.Errorf("Classify(%q) = %s, want %s", tt.status, got, tt.want)
+		}
+	}
+}
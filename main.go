@@ -6,8 +6,13 @@ import (
 	"os"
 	"time"
 
+	"github.com/Nxdus/hatyai-api/category"
+	"github.com/Nxdus/hatyai-api/history"
+	"github.com/Nxdus/hatyai-api/lifecycle"
+	"github.com/Nxdus/hatyai-api/priority"
 	"github.com/Nxdus/hatyai-api/routes"
 	"github.com/Nxdus/hatyai-api/services"
+	"github.com/Nxdus/hatyai-api/trends"
 	"github.com/gofiber/fiber/v2"
 	"github.com/redis/go-redis/v9"
 )
@@ -41,8 +46,30 @@ func main() {
 		log.Printf("Redis connected: %s", redisAddr)
 	}
 
+	if path := os.Getenv("PRIORITY_RULES_FILE"); path != "" {
+		if err := priority.WatchRules(path, 10*time.Second); err != nil {
+			log.Printf("Priority rules load failed, using built-in rules: %v", err)
+		}
+	}
+
+	if path := os.Getenv("STATUS_MAP_FILE"); path != "" {
+		if err := lifecycle.WatchMapping(path, 10*time.Second); err != nil {
+			log.Printf("Status mapping load failed, using built-in mapping: %v", err)
+		}
+	}
+
+	if path := os.Getenv("CATEGORY_TAXONOMY_FILE"); path != "" {
+		if err := category.WatchTaxonomy(path, 10*time.Second); err != nil {
+			log.Printf("Category taxonomy load failed, using built-in taxonomy: %v", err)
+		}
+	}
+
 	fetcher := services.NewHTTPFetcher()
 	sosService := services.NewRedisSOSService(rdb, fetcher)
+	trendStore := trends.NewStore(rdb)
+	historyStore := history.NewStore(rdb)
+	sosService.OnRefresh(trendStore.Record)
+	sosService.OnRefresh(historyStore.Record)
 
 	go func() {
 		if _, err := sosService.GetRaw(); err != nil {
@@ -54,7 +81,7 @@ func main() {
 
 	startCacheRefresher(sosService, 45*time.Second)
 
-	routes.RegisterRoutes(app, sosService, rdb)
+	routes.RegisterRoutes(app, sosService, rdb, trendStore, historyStore)
 
 	log.Fatal(app.Listen(":3000"))
 }
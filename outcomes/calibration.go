@@ -0,0 +1,191 @@
+package outcomes
+
+import "sort"
+
+// LevelStats compares how often a level was predicted with how often
+// responders found it. Precision is the share of predictions of the level
+// that were right, Recall the share of actual cases that were predicted.
+type LevelStats struct {
+	Level     string  `json:"level"`
+	Predicted int     `json:"predicted"`
+	Actual    int     `json:"actual"`
+	Correct   int     `json:"correct"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+}
+
+// RuleStats shows how the items a rule added points to turned out. A rule
+// that mostly fires on over-triaged items is weighted too high, one that
+// fires on under-triaged items too low.
+type RuleStats struct {
+	RuleID       string         `json:"rule_id"`
+	Fired        int            `json:"fired"`
+	MeanPoints   float64        `json:"mean_points"`
+	Actual       map[string]int `json:"actual"`
+	Correct      int            `json:"correct"`
+	OverTriaged  int            `json:"over_triaged"`
+	UnderTriaged int            `json:"under_triaged"`
+}
+
+// Report compares predicted levels with recorded outcomes. Confusion is
+// keyed by predicted level, then by actual level. Over-triaged items were
+// predicted more severe than found, under-triaged less severe.
+type Report struct {
+	Count        int                       `json:"count"`
+	Levels       []string                  `json:"levels"`
+	Accuracy     float64                   `json:"accuracy"`
+	OverTriaged  int                       `json:"over_triaged"`
+	UnderTriaged int                       `json:"under_triaged"`
+	Confusion    map[string]map[string]int `json:"confusion"`
+	PerLevel     []LevelStats              `json:"per_level"`
+	Rules        []RuleStats               `json:"rules"`
+}
+
+// AllVersions selects outcomes from every rule version in Select.
+const AllVersions = "all"
+
+// Select returns the outcomes predicted under profile and rule version,
+// keeping their order. A version of AllVersions matches any version.
+func Select(list []Outcome, profile, version string) []Outcome {
+	selected := make([]Outcome, 0, len(list))
+	for _, o := range list {
+		if o.Profile != profile || (version != AllVersions && o.RuleVersion != version) {
+			continue
+		}
+		selected = append(selected, o)
+	}
+	return selected
+}
+
+// Calibrate builds a report over outcomes. levels lists the level names from
+// most to least severe; a level outside it ranks below all of them.
+func Calibrate(list []Outcome, levels []string) *Report {
+	rank := make(map[string]int, len(levels))
+	for i, lv := range levels {
+		rank[lv] = i
+	}
+	severity := func(level string) int {
+		if r, ok := rank[level]; ok {
+			return r
+		}
+		return len(levels)
+	}
+
+	report := &Report{
+		Count:     len(list),
+		Levels:    levels,
+		Confusion: make(map[string]map[string]int, len(levels)),
+		PerLevel:  make([]LevelStats, 0, len(levels)),
+		Rules:     make([]RuleStats, 0),
+	}
+	for _, lv := range levels {
+		report.Confusion[lv] = make(map[string]int, len(levels))
+		for _, actual := range levels {
+			report.Confusion[lv][actual] = 0
+		}
+	}
+
+	perLevel := make(map[string]*LevelStats, len(levels))
+	levelStats := func(level string) *LevelStats {
+		ls, ok := perLevel[level]
+		if !ok {
+			ls = &LevelStats{Level: level}
+			perLevel[level] = ls
+		}
+		return ls
+	}
+	rules := make(map[string]*RuleStats)
+	var ruleOrder []string
+	pointSums := make(map[string]float64)
+	correct := 0
+
+	for _, o := range list {
+		predicted := o.Predicted.Level
+		if report.Confusion[predicted] == nil {
+			report.Confusion[predicted] = make(map[string]int)
+		}
+		report.Confusion[predicted][o.Level]++
+		levelStats(predicted).Predicted++
+		levelStats(o.Level).Actual++
+
+		diff := severity(predicted) - severity(o.Level)
+		switch {
+		case diff == 0:
+			correct++
+			levelStats(predicted).Correct++
+		case diff < 0:
+			report.OverTriaged++
+		default:
+			report.UnderTriaged++
+		}
+
+		for _, c := range o.Predicted.Contributions {
+			if c.Points == 0 {
+				continue
+			}
+			rs, ok := rules[c.RuleID]
+			if !ok {
+				rs = &RuleStats{RuleID: c.RuleID, Actual: make(map[string]int)}
+				rules[c.RuleID] = rs
+				ruleOrder = append(ruleOrder, c.RuleID)
+			}
+			rs.Fired++
+			pointSums[c.RuleID] += c.Points
+			rs.Actual[o.Level]++
+			switch {
+			case diff == 0:
+				rs.Correct++
+			case diff < 0:
+				rs.OverTriaged++
+			default:
+				rs.UnderTriaged++
+			}
+		}
+	}
+
+	if len(list) > 0 {
+		report.Accuracy = ratio(correct, len(list))
+	}
+	seen := make(map[string]bool, len(perLevel))
+	appendLevel := func(level string) {
+		ls, ok := perLevel[level]
+		if !ok || seen[level] {
+			return
+		}
+		seen[level] = true
+		ls.Precision = ratio(ls.Correct, ls.Predicted)
+		ls.Recall = ratio(ls.Correct, ls.Actual)
+		report.PerLevel = append(report.PerLevel, *ls)
+	}
+	for _, lv := range levels {
+		if _, ok := perLevel[lv]; !ok {
+			perLevel[lv] = &LevelStats{Level: lv}
+		}
+		appendLevel(lv)
+	}
+	for _, o := range list {
+		appendLevel(o.Predicted.Level)
+		appendLevel(o.Level)
+	}
+
+	sort.SliceStable(ruleOrder, func(i, j int) bool {
+		if rules[ruleOrder[i]].Fired != rules[ruleOrder[j]].Fired {
+			return rules[ruleOrder[i]].Fired > rules[ruleOrder[j]].Fired
+		}
+		return ruleOrder[i] < ruleOrder[j]
+	})
+	for _, id := range ruleOrder {
+		rs := rules[id]
+		rs.MeanPoints = float64(int(pointSums[id]/float64(rs.Fired)*100+0.5)) / 100
+		report.Rules = append(report.Rules, *rs)
+	}
+	return report
+}
+
+// ratio returns n/d rounded to three places, or 0 when d is 0.
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(int(float64(n)/float64(d)*1000+0.5)) / 1000
+}
@@ -0,0 +1,148 @@
+package outcomes
+
+import (
+	"testing"
+
+	"github.com/Nxdus/hatyai-api/priority"
+)
+
+var levels = []string{"critical", "high", "medium", "low"}
+
+func outcome(id, predicted, actual string, rules ...string) Outcome {
+	o := Outcome{ID: id, Level: actual, RuleVersion: "v1", Profile: "default"}
+	o.Predicted.Level = predicted
+	for _, r := range rules {
+		o.Predicted.Contributions = append(o.Predicted.Contributions, priority.Contribution{RuleID: r, Points: 10})
+	}
+	return o
+}
+
+func TestCalibrate(t *testing.T) {
+	tests := []struct {
+		name              string
+		list              []Outcome
+		accuracy          float64
+		over, under       int
+		predicted, actual string
+		confusion         int
+		precision, recall float64
+		level             string
+	}{
+		{
+			name:      "empty",
+			predicted: "critical", actual: "critical",
+			level: "critical",
+		},
+		{
+			name: "correct, over and under triaged",
+			list: []Outcome{
+				outcome("a", "critical", "critical"),
+				outcome("b", "critical", "low"),
+				outcome("c", "medium", "high"),
+				outcome("d", "high", "high"),
+			},
+			accuracy: 0.5, over: 1, under: 1,
+			predicted: "critical", actual: "low", confusion: 1,
+			level: "critical", precision: 0.5, recall: 1,
+		},
+		{
+			name: "level outside the profile ranks below all",
+			list: []Outcome{
+				outcome("a", "low", "unknown"),
+			},
+			over:      1,
+			predicted: "low", actual: "unknown", confusion: 1,
+			level: "unknown",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Calibrate(tt.list, levels)
+			if r.Count != len(tt.list) || r.Accuracy != tt.accuracy || r.OverTriaged != tt.over || r.UnderTriaged != tt.under {
+				t.Errorf("count=%d accuracy=%v over=%d under=%d, want %d %v %d %d",
+					r.Count, r.Accuracy, r.OverTriaged, r.UnderTriaged, len(tt.list), tt.accuracy, tt.over, tt.under)
+			}
+			if got := r.Confusion[tt.predicted][tt.actual]; got != tt.confusion {
+				t.Errorf("confusion[%s][%s] = %d, want %d", tt.predicted, tt.actual, got, tt.confusion)
+			}
+			var found bool
+			for _, ls := range r.PerLevel {
+				if ls.Level != tt.level {
+					continue
+				}
+				found = true
+				if ls.Precision != tt.precision || ls.Recall != tt.recall {
+					t.Errorf("per_level %s precision=%v recall=%v, want %v %v", ls.Level, ls.Precision, ls.Recall, tt.precision, tt.recall)
+				}
+			}
+			if !found {
+				t.Errorf("per_level has no %s", tt.level)
+			}
+		})
+	}
+}
+
+func TestCalibrateRules(t *testing.T) {
+	r := Calibrate([]Outcome{
+		outcome("a", "critical", "critical", "keyword.urgent", "age_band.elderly"),
+		outcome("b", "critical", "low", "keyword.urgent"),
+		outcome("c", "low", "high", "age_band.elderly"),
+		outcome("d", "low", "low", "keyword.urgent"),
+	}, levels)
+
+	want := []RuleStats{
+		{RuleID: "keyword.urgent", Fired: 3, MeanPoints: 10, Correct: 2, OverTriaged: 1},
+		{RuleID: "age_band.elderly", Fired: 2, MeanPoints: 10, Correct: 1, UnderTriaged: 1},
+	}
+	if len(r.Rules) != len(want) {
+		t.Fatalf("rules = %+v, want %d rules", r.Rules, len(want))
+	}
+	for i, w := range want {
+		got := r.Rules[i]
+		if got.RuleID != w.RuleID || got.Fired != w.Fired || got.MeanPoints != w.MeanPoints ||
+			got.Correct != w.Correct || got.OverTriaged != w.OverTriaged || got.UnderTriaged != w.UnderTriaged {
+			t.Errorf("rules[%d] = %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	at := func(id, profile, version string) Outcome {
+		o := outcome(id, "high", "high")
+		o.Profile, o.RuleVersion = profile, version
+		return o
+	}
+	list := []Outcome{
+		at("a", "default", "v1"),
+		at("b", "default", "v2"),
+		at("c", "flood", "v2"),
+		at("d", "default", "v2"),
+	}
+	tests := []struct {
+		profile, version string
+		want             []string
+	}{
+		{"default", "v2", []string{"b", "d"}},
+		{"default", "v1", []string{"a"}},
+		{"default", AllVersions, []string{"a", "b", "d"}},
+		{"flood", "v1", nil},
+		{"flood", AllVersions, []string{"c"}},
+	}
+	for _, tt := range tests {
+		got := Select(list, tt.profile, tt.version)
+		var ids []string
+		for _, o := range got {
+			ids = append(ids, o.ID)
+		}
+		if len(ids) != len(tt.want) {
+			t.Errorf("Select(%s, %s) = %v, want %v", tt.profile, tt.version, ids, tt.want)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.want[i] {
+				t.Errorf("Select(%s, %s) = %v, want %v", tt.profile, tt.version, ids, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package outcomes
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/Nxdus/hatyai-api/priority"
+	"github.com/redis/go-redis/v9"
+)
+
+const redisKeyOutcomes = "request:outcomes"
+
+// Outcome is the severity a responder found on arrival, recorded next to the
+// priority the item had when the outcome was recorded and the rule version
+// and profile that priority came from. Recording again for the same item
+// replaces the earlier outcome.
+type Outcome struct {
+	ID            string          `json:"_id"`
+	RunningNumber string          `json:"running_number"`
+	Level         string          `json:"level"`
+	Note          string          `json:"note,omitempty"`
+	RecordedBy    string          `json:"recorded_by,omitempty"`
+	RecordedAt    time.Time       `json:"recorded_at"`
+	RuleVersion   string          `json:"rule_version"`
+	Profile       string          `json:"profile"`
+	Predicted     priority.Result `json:"predicted"`
+}
+
+type Store struct {
+	redis *redis.Client
+}
+
+func NewStore(redis *redis.Client) *Store {
+	return &Store{redis: redis}
+}
+
+func (s *Store) Save(o Outcome) error {
+	b, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return s.redis.HSet(ctx, redisKeyOutcomes, o.ID, b).Err()
+}
+
+// All returns every recorded outcome, most recent first.
+func (s *Store) All() ([]Outcome, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	stored, err := s.redis.HGetAll(ctx, redisKeyOutcomes).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Outcome, 0, len(stored))
+	for _, raw := range stored {
+		var o Outcome
+		if json.Unmarshal([]byte(raw), &o) == nil {
+			list = append(list, o)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].RecordedAt.After(list[j].RecordedAt)
+	})
+	return list, nil
+}
@@ -0,0 +1,195 @@
+package priority
+
+import (
+	"strconv"
+	"unicode"
+
+	"github.com/Nxdus/hatyai-api/textnorm"
+)
+
+// ageUnit is a word that follows a number in an ages string, with the
+// number of years one of it is worth.
+type ageUnit struct {
+	word  string
+	years float64
+}
+
+// Longer words come first so "เดือน" is not read as something shorter and
+// "years" is tried before "yr".
+var ageUnits = []ageUnit{
+	{"สัปดาห์", 1.0 / 52}, {"อาทิตย์", 1.0 / 52}, {"เดือน", 1.0 / 12}, {"ขวบ", 1}, {"ปี", 1}, {"วัน", 1.0 / 365},
+	{"months", 1.0 / 12}, {"month", 1.0 / 12}, {"mos", 1.0 / 12}, {"mo", 1.0 / 12},
+	{"weeks", 1.0 / 52}, {"week", 1.0 / 52}, {"wks", 1.0 / 52}, {"wk", 1.0 / 52},
+	{"years", 1}, {"year", 1}, {"yrs", 1}, {"yr", 1}, {"y", 1},
+	{"days", 1.0 / 365}, {"day", 1.0 / 365},
+}
+
+// Words after a number that make it a head count rather than an age.
+var countWords = []string{"คน", "ราย", "ท่าน", "people", "persons", "person", "pax"}
+
+var rangeWords = []string{"-", "–", "~", "ถึง", "to"}
+
+var newbornWords = []string{"แรกเกิด", "newborn"}
+
+const maxAge = 120
+
+type ageToken struct {
+	value     float64
+	unit      float64 // years per unit, 0 when no unit was written
+	rangeNext bool    // the next token ends a range that starts here
+	joinNext  bool    // the next token follows this one's unit directly
+}
+
+// ParseAges reads every age in an upstream ages string, in years and in the
+// order written. It understands Thai digits, units ("3 เดือน", "2 ขวบครึ่ง",
+// "10 days"), lists ("45,80", "5 และ 70"), ranges ("60-70", where both ends
+// are returned and a unit on the second end applies to both) and
+// "แรกเกิด" (newborn). A number with a smaller unit right after another's
+// unit adds to it ("1 ปี 6 เดือน" is 1.5). Numbers without a unit are years;
+// numbers followed by a head count word ("2 คน"), phone numbers
+// ("08-1234-5678") and anything over 120 years are skipped.
+func ParseAges(val string) []float64 {
+	runes := []rune(textnorm.Normalize(val))
+	var tokens []ageToken
+
+	for i := 0; i < len(runes); {
+		if w, ok := wordAt(runes, i, newbornWords); ok {
+			tokens = append(tokens, ageToken{value: 0, unit: 1})
+			i += len([]rune(w))
+			continue
+		}
+		if !isDigit(runes[i]) {
+			i++
+			continue
+		}
+		if end, ok := phoneAt(runes, i); ok {
+			i = end
+			continue
+		}
+
+		j := i
+		for j < len(runes) && (isDigit(runes[j]) || (runes[j] == '.' && j+1 < len(runes) && isDigit(runes[j+1]) && j > i)) {
+			j++
+		}
+		n, err := strconv.ParseFloat(string(runes[i:j]), 64)
+		tok := ageToken{value: n}
+
+		k := skipSpaces(runes, j)
+		if _, ok := wordAt(runes, k, countWords); ok {
+			i = j
+			continue
+		}
+		for _, u := range ageUnits {
+			if hasWord(runes, k, u.word) {
+				tok.unit = u.years
+				k = skipSpaces(runes, k+len([]rune(u.word)))
+				if hasWord(runes, k, "ครึ่ง") {
+					tok.value += 0.5
+					k = skipSpaces(runes, k+len([]rune("ครึ่ง")))
+				}
+				j = k
+				tok.joinNext = k < len(runes) && isDigit(runes[k])
+				break
+			}
+		}
+		if w, ok := wordAt(runes, k, rangeWords); ok {
+			if next := skipSpaces(runes, k+len([]rune(w))); next < len(runes) && isDigit(runes[next]) {
+				tok.rangeNext = true
+				j = next
+			}
+		}
+
+		if err == nil {
+			tokens = append(tokens, tok)
+		}
+		i = j
+	}
+
+	for i := len(tokens) - 2; i >= 0; i-- {
+		if tokens[i].rangeNext && tokens[i].unit == 0 {
+			tokens[i].unit = tokens[i+1].unit
+		}
+	}
+
+	ages := make([]float64, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		unit := tok.unit
+		if unit == 0 {
+			unit = 1
+		}
+		age := tok.value * unit
+		for tok.joinNext && !tok.rangeNext && i+1 < len(tokens) && tokens[i+1].unit > 0 && tokens[i+1].unit < tok.unit {
+			i++
+			tok = tokens[i]
+			age += tok.value * tok.unit
+		}
+		if age > maxAge || (age == 0 && tok.unit == 0) {
+			continue
+		}
+		ages = append(ages, age)
+	}
+	return ages
+}
+
+// phoneAt reports whether a phone number, 9 or 10 digits starting with 0
+// and split by at most single "-", "." or " " separators, starts at
+// runes[i], and where it ends.
+func phoneAt(runes []rune, i int) (int, bool) {
+	if runes[i] != '0' || (i > 0 && isDigit(runes[i-1])) {
+		return 0, false
+	}
+	digits, j := 0, i
+	for j < len(runes) {
+		if isDigit(runes[j]) {
+			digits++
+			j++
+			continue
+		}
+		sep := runes[j] == '-' || runes[j] == '.' || runes[j] == ' '
+		if !sep || j+1 == len(runes) || !isDigit(runes[j+1]) {
+			break
+		}
+		j++
+	}
+	return j, digits == 9 || digits == 10
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func skipSpaces(runes []rune, i int) int {
+	for i < len(runes) && runes[i] == ' ' {
+		i++
+	}
+	return i
+}
+
+func wordAt(runes []rune, i int, words []string) (string, bool) {
+	for _, w := range words {
+		if hasWord(runes, i, w) {
+			return w, true
+		}
+	}
+	return "", false
+}
+
+// hasWord reports whether word starts at runes[i]. A Latin word must also
+// end there, so "y" does not match the start of "yes".
+func hasWord(runes []rune, i int, word string) bool {
+	w := []rune(word)
+	if i+len(w) > len(runes) || string(runes[i:i+len(w)]) != word {
+		return false
+	}
+	end := i + len(w)
+	if textnorm.IsThai(w[len(w)-1]) || end == len(runes) {
+		return true
+	}
+	return !unicode.IsLetter(runes[end])
+}
+
+// formatAge renders an age in years for a reason, to one decimal place.
+func formatAge(age float64) string {
+	return strconv.FormatFloat(float64(int(age*10+0.5))/10, 'f', -1, 64)
+}
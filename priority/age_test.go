@@ -0,0 +1,37 @@
+package priority
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAges(t *testing.T) {
+	tests := []struct {
+		val  string
+		want []float64
+	}{
+		{"", []float64{}},
+		{"45,80", []float64{45, 80}},
+		{"5 และ 70", []float64{5, 70}},
+		{"๗๐", []float64{70}},
+		{"60-70", []float64{60, 70}},
+		{"6-8 เดือน", []float64{0.5, 8.0 / 12}},
+		{"3 เดือน", []float64{0.25}},
+		{"2 ขวบครึ่ง", []float64{2.5}},
+		{"แรกเกิด", []float64{0}},
+		{"2 คน", []float64{}},
+		{"150", []float64{}},
+		{"70 ปี 6 เดือน", []float64{70.5}},
+		{"1 ปี 6 เดือน", []float64{1.5}},
+		{"1 year 6 months, 40", []float64{1.5, 40}},
+		{"6 เดือน 1 ปี", []float64{0.5, 1}},
+		{"08-1234-5678", []float64{}},
+		{"081 234 5678 อายุ 80", []float64{80}},
+		{"0812345678", []float64{}},
+	}
+	for _, tt := range tests {
+		if got := ParseAges(tt.val); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseAges(%q) = %v, want %v", tt.val, got, tt.want)
+		}
+	}
+}
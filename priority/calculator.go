@@ -7,33 +7,51 @@ import (
 	"time"
 
 	"github.com/Nxdus/hatyai-api/services"
+	"github.com/Nxdus/hatyai-api/textnorm"
 )
 
 type Result struct {
-	Score   int      `json:"score"`
-	Level   string   `json:"level"`
-	Reasons []string `json:"reasons"`
+	Score         int            `json:"score"`
+	Level         string         `json:"level"`
+	Reasons       []string       `json:"reasons"`
+	Contributions []Contribution `json:"contributions"`
+	RuleVersion   string         `json:"rule_version"`
+	Profile       string         `json:"profile"`
 }
 
-func Calculate(prop services.LocationProperty) Result {
-	var score float64
-	var reasons []string
+// Options controls how a result is produced. Now is the evaluation time
+// that recency and waiting time are measured against; callers scoring a
+// snapshot pass its fetched_at so the same snapshot always scores the same.
+// StatusSince holds, by item _id, when an item entered its current status;
+// items not in it are taken to have had it since created_at. The zero value
+// renders Thai reasons as of the current time.
+type Options struct {
+	Lang        string
+	Now         time.Time
+	StatusSince map[string]time.Time
+}
+
+// Calculate scores item with the active rules as of at.
+func Calculate(item services.DataItem, at time.Time) Result {
+	return Evaluate(ActiveRules(), item, Options{Now: at})
+}
 
-	otherText := strings.ToLower(prop.Other)
+// Evaluate scores item against an explicit rule set.
+func Evaluate(rules *Rules, item services.DataItem, opts Options) Result {
+	var contribs []Contribution
+	prop := item.Location.Properties
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
 
-	switch prop.SickLevelSummary {
-	case 4:
-		score += 55
-		reasons = append(reasons, "ระดับความเจ็บป่วย: 4")
-	case 3:
-		score += 45
-		reasons = append(reasons, "ระดับความเจ็บป่วย: 3")
-	case 2:
-		score += 30
-		reasons = append(reasons, "ระดับความเจ็บป่วย: 2")
-	case 1:
-		score += 15
-		reasons = append(reasons, "ระดับความเจ็บป่วย: 1")
+	if points, ok := rules.SickLevels[strconv.Itoa(prop.SickLevelSummary)]; ok {
+		contribs = append(contribs, Contribution{
+			RuleID:   "sick_level." + strconv.Itoa(prop.SickLevelSummary),
+			Category: CategorySickLevel,
+			Value:    strconv.Itoa(prop.SickLevelSummary),
+			Points:   points,
+		})
 	}
 
 	patientCount := prop.Patient
@@ -41,92 +59,110 @@ func Calculate(prop services.LocationProperty) Result {
 		patientCount = len(prop.Victims)
 	}
 	if patientCount > 0 {
-		weight := float64(patientCount)
-		if weight > 10 {
-			weight = 10
+		weight := patientCount
+		if rules.Patients.Cap > 0 && weight > rules.Patients.Cap {
+			weight = rules.Patients.Cap
+		}
+		contribs = append(contribs, Contribution{
+			RuleID:   "patients",
+			Category: CategoryPatients,
+			Value:    strconv.Itoa(patientCount),
+			Points:   float64(weight) * rules.Patients.PointsPerPerson,
+		})
+	}
+
+	// Age bands use the victims' own ages when they have them, falling back
+	// to every age in the ages field. The first band any age falls in is
+	// scored, so the most vulnerable person counts, and is not counted
+	// again in a vulnerable group by age.
+	ages, owners := victimAges(prop.Victims)
+	if len(ages) == 0 {
+		ages, owners = ParseAges(prop.Ages), nil
+	}
+	banded := -1
+bands:
+	for i, band := range rules.AgeBands {
+		for j, age := range ages {
+			if age >= float64(band.Min) && (band.Max == 0 || age < float64(band.Max)) {
+				contribs = append(contribs, Contribution{
+					RuleID:   ruleID("age_band", band.ID, i),
+					Category: CategoryAge,
+					Value:    formatAge(age),
+					Points:   band.Points,
+					labels:   [2]string{band.Label, band.LabelEN},
+				})
+				if owners != nil {
+					banded = owners[j]
+				}
+				break bands
+			}
+		}
+	}
+
+	victimContribs, groups := rules.scoreVictims(prop.Victims, banded)
+	contribs = append(contribs, victimContribs...)
+
+	var diseaseTokens []textnorm.Token
+	hits, discounted := rules.Disease.hits(textnorm.Compact(prop.Disease), &diseaseTokens, rules.dict, &rules.Context, CategoryDisease)
+	contribs = append(contribs, scoreHits(distinctHits(hits), 0, CategoryDisease)...)
+	contribs = append(contribs, discounted...)
+
+	waiting, waitingHours := rules.Waiting.hours(item, opts.StatusSince, now)
+	// An update after now, from clock skew or a what_if_at time, says nothing
+	// about recency as of now.
+	if t, ok := parseTime(prop.UpdatedAt); ok && !t.After(now) {
+		hours := now.Sub(t).Hours()
+		for i, rule := range rules.Recency {
+			if waiting && rule.Points < 0 {
+				continue
+			}
+			if (rule.MinHours == 0 || hours > rule.MinHours) && (rule.MaxHours == 0 || hours <= rule.MaxHours) {
+				contribs = append(contribs, Contribution{
+					RuleID:   ruleID("recency", rule.ID, i),
+					Category: CategoryRecency,
+					Value:    strconv.FormatFloat(math.Floor(hours), 'f', 0, 64),
+					Points:   rule.Points,
+					labels:   [2]string{rule.Label, rule.LabelEN},
+				})
+				break
+			}
 		}
-		score += weight * 2
-		reasons = append(reasons, "มีผู้ป่วยจำนวน "+strconv.Itoa(patientCount)+" คน")
-	}
-
-	age := parseAge(prop.Ages)
-	if age > 0 && (age < 6 || age >= 70) {
-		score += 8
-		reasons = append(reasons, "อายุเสี่ยง: "+strconv.Itoa(age)+" ปี")
-	}
-
-	disease := strings.ToLower(prop.Disease)
-	severeKeywords := []string{
-		"หัวใจ",
-		"หัวใจหยุด",
-		"เส้นเลือด",
-		"หลอดเลือดสมอง",
-		"มะเร็ง",
-		"ฟอกไต",
-		"เครื่องช่วยหายใจ",
-	}
-	if kw, ok := findKeyword(disease, severeKeywords...); ok {
-		score += 8
-		reasons = append(reasons, "โรคประจำตัว: "+kw)
-	}
-
-	if t, ok := parseTime(prop.UpdatedAt); ok {
-		hours := time.Since(t).Hours()
-		switch {
-		case hours <= 24:
-			score += 6
-			reasons = append(reasons, "อัพเดตในช่วง 24 ชั่วโมงที่ผ่านมา")
-		case hours > 72:
-			score -= 5
-			reasons = append(reasons, "ไม่มีการอัพเดตเกิน 72 ชั่วโมง")
+	}
+
+	if waiting {
+		if points := rules.Waiting.points(waitingHours); points != 0 {
+			contribs = append(contribs, Contribution{
+				RuleID:   ruleID("waiting", rules.Waiting.ID, 0),
+				Category: CategoryWaiting,
+				Value:    strconv.FormatFloat(math.Floor(waitingHours), 'f', 0, 64),
+				Points:   points,
+				labels:   [2]string{rules.Waiting.Label, rules.Waiting.LabelEN},
+			})
 		}
 	}
 
-	if kw, ok := findKeyword(
-		otherText,
-		"หมดสติ",
-		"หัวใจหยุด",
-		"วิกฤต",
-		"ช่วยด่วน",
-		"ฟอกไต",
-		"หายใจไม่ออก",
-		"เลือดออกมาก",
-		"เสียเลือด",
-		"หยุดหายใจ",
-		"ช็อก",
-		"ชัก",
-		"ไม่รู้สึกตัว",
-		"บาดเจ็บหนัก",
-		"กระดูกหัก",
-	); ok {
-		score += 12
-		reasons = append(reasons, "มีคีย์เวิร์ดรุนแรง: "+kw)
-
-	} else if kw, ok := findKeyword(
-		otherText,
-		"ติดเตียง",
-		"พิการ",
-		"ใกล้คลอด",
-		"เด็กเล็ก",
-		"ผู้สูงอายุ",
-		"ทารกแรกเกิด",
-	); ok {
-		score += 8
-		reasons = append(reasons, "มีคีย์เวิร์ดเสี่ยง: "+kw)
-	} else if kw, ok := findKeyword(
-		otherText,
-		"ขาดอาหาร",
-		"ขาดน้ำ",
-		"ขาดยา",
-		"ขาดไฟ",
-		"ติดต่อไม่ได้",
-		"ตัดขาด",
-		"ติดอยู่",
-		"ไม่มีสัญญาณ",
-		"ไฟดับ",
-	); ok {
-		score += 5
-		reasons = append(reasons, "มีคีย์เวิร์ดต้องการความช่วยเหลือ: "+kw)
+	otherText := textnorm.Compact(prop.Other)
+	var otherTokens []textnorm.Token
+	var otherHits []keywordHit
+	var otherDiscounted []Contribution
+	for i := range rules.KeywordTiers {
+		hits, discounted := rules.KeywordTiers[i].hits(otherText, &otherTokens, rules.dict, &rules.Context, CategoryKeyword)
+		otherHits = append(otherHits, hits...)
+		otherDiscounted = append(otherDiscounted, discounted...)
+	}
+	// A vulnerable group already scored from the victims is not scored
+	// again for being named in other.
+	otherHits, counted := countedHits(distinctHits(otherHits), groups)
+	contribs = append(contribs, scoreHits(otherHits, rules.KeywordCap, CategoryKeyword)...)
+	contribs = append(contribs, counted...)
+	contribs = append(contribs, otherDiscounted...)
+
+	var score float64
+	reasons := make([]string, 0, len(contribs))
+	for i := range contribs {
+		score += contribs[i].Points
+		contribs[i].Text = contribs[i].render(opts.Lang)
+		reasons = append(reasons, contribs[i].Text)
 	}
 
 	if score < 0 {
@@ -136,46 +172,104 @@ func Calculate(prop services.LocationProperty) Result {
 		score = 100
 	}
 
-	level := "low"
-	switch {
-	case score >= 75:
-		level = "critical"
-	case score >= 55:
-		level = "high"
-	case score >= 35:
-		level = "medium"
+	return Result{
+		Score:         int(math.Round(score)),
+		Level:         rules.level(score),
+		Reasons:       reasons,
+		Contributions: contribs,
+		RuleVersion:   rules.Version,
+		Profile:       rules.Profile,
 	}
+}
 
-	return Result{
-		Score:   int(math.Round(score)),
-		Level:   level,
-		Reasons: reasons,
+// scoreHits turns keyword hits into contributions, applying each tier's cap
+// and then the overall cap (0 means uncapped). Keywords past a cap are kept
+// with zero points and status "capped".
+func scoreHits(hits []keywordHit, overallCap float64, category string) []Contribution {
+	var total float64
+	contribs := make([]Contribution, 0, len(hits))
+	tierTotals := make(map[*KeywordTier]float64)
+	for _, h := range hits {
+		points := h.points
+		if h.tier.Cap > 0 {
+			points = math.Min(points, h.tier.Cap-tierTotals[h.tier])
+		}
+		if overallCap > 0 {
+			points = math.Min(points, overallCap-total)
+		}
+
+		c := Contribution{
+			RuleID:   h.tier.ruleID(category),
+			Category: category,
+			Value:    h.keyword.Term,
+			labels:   [2]string{h.tier.Label, h.tier.LabelEN},
+		}
+		if points <= 0 {
+			c.Status = StatusCapped
+		} else {
+			c.Points = points
+			tierTotals[h.tier] += points
+			total += points
+		}
+		contribs = append(contribs, c)
 	}
+	return contribs
 }
 
-func parseAge(val string) int {
-	val = strings.TrimSpace(val)
-	if val == "" {
-		return 0
+// ruleID names a keyword tier in contributions: the disease tier is
+// "disease", tiers scanned over other are "keyword.<id>".
+func (t *KeywordTier) ruleID(category string) string {
+	if category == CategoryKeyword {
+		return CategoryKeyword + "." + t.ID
 	}
+	return category
+}
+
+func ruleID(kind, id string, index int) string {
+	if id != "" {
+		return kind + "." + id
+	}
+	return kind + "." + strconv.Itoa(index)
+}
 
-	builder := strings.Builder{}
-	for _, r := range val {
-		if r >= '0' && r <= '9' {
-			builder.WriteRune(r)
-		} else if builder.Len() > 0 {
-			break
+// hours reports whether item is in a waiting status and for how many hours
+// it has had that status as of now.
+func (w *WaitingRule) hours(item services.DataItem, statusSince map[string]time.Time, now time.Time) (bool, float64) {
+	if _, ok := w.statuses[textnorm.Compact(item.Location.Properties.StatusText)]; !ok {
+		return false, 0
+	}
+	since, ok := statusSince[item.ID]
+	if !ok {
+		if since, ok = parseTime(item.CreatedAt); !ok {
+			return true, 0
 		}
 	}
-	if builder.Len() == 0 {
+	return true, math.Max(now.Sub(since).Hours(), 0)
+}
+
+// points reads the waiting curve at hours.
+func (w *WaitingRule) points(hours float64) float64 {
+	curve := w.Curve
+	if len(curve) == 0 || hours < curve[0].Hours {
 		return 0
 	}
+	for i := 1; i < len(curve); i++ {
+		if hours < curve[i].Hours {
+			a, b := curve[i-1], curve[i]
+			points := a.Points + (b.Points-a.Points)*(hours-a.Hours)/(b.Hours-a.Hours)
+			return math.Round(points*10) / 10
+		}
+	}
+	return curve[len(curve)-1].Points
+}
 
-	age, err := strconv.Atoi(builder.String())
-	if err != nil {
-		return 0
+func (r *Rules) level(score float64) string {
+	for _, lv := range r.Levels {
+		if score >= lv.MinScore {
+			return lv.Name
+		}
 	}
-	return age
+	return r.Levels[len(r.Levels)-1].Name
 }
 
 func parseTime(val string) (time.Time, bool) {
@@ -190,16 +284,3 @@ func parseTime(val string) (time.Time, bool) {
 	}
 	return t, true
 }
-
-func findKeyword(text string, keywords ...string) (string, bool) {
-	if text == "" {
-		return "", false
-	}
-	lowerText := strings.ToLower(text)
-	for _, kw := range keywords {
-		if strings.Contains(lowerText, strings.ToLower(kw)) {
-			return kw, true
-		}
-	}
-	return "", false
-}
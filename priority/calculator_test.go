@@ -0,0 +1,87 @@
+package priority
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nxdus/hatyai-api/services"
+)
+
+func TestEvaluateTime(t *testing.T) {
+	now := time.Date(2025, 11, 28, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) string { return now.Add(d).Format(time.RFC3339) }
+
+	tests := []struct {
+		name        string
+		status      string
+		created     string
+		updated     string
+		statusSince time.Time
+		want        map[string]float64 // rule_id to points, for recency and waiting
+	}{
+		{"updated an hour ago", "", at(-48 * time.Hour), at(-time.Hour), time.Time{}, map[string]float64{"recency.recent": 6}},
+		{"updated four days ago", "", at(-96 * time.Hour), at(-96 * time.Hour), time.Time{}, map[string]float64{"recency.stale": -5}},
+		{"updated in between", "", at(-48 * time.Hour), at(-48 * time.Hour), time.Time{}, map[string]float64{}},
+		{"updated after now", "", at(-48 * time.Hour), at(2 * time.Hour), time.Time{}, map[string]float64{}},
+		{"waiting since created", "รอรับเรื่อง", at(-72 * time.Hour), "", time.Time{}, map[string]float64{"waiting.waiting": 12}},
+		{"waiting since status change", "รอรับเรื่อง", at(-168 * time.Hour), "", now.Add(-24 * time.Hour), map[string]float64{"waiting.waiting": 4}},
+		{"waiting not stale", "รอรับเรื่อง", at(-96 * time.Hour), at(-96 * time.Hour), time.Time{}, map[string]float64{"waiting.waiting": 14}},
+		{"waiting from the future", "รอรับเรื่อง", at(time.Hour), "", time.Time{}, map[string]float64{}},
+	}
+	for _, tt := range tests {
+		var item services.DataItem
+		item.ID = "a1"
+		item.CreatedAt = tt.created
+		item.Location.Properties.StatusText = tt.status
+		item.Location.Properties.UpdatedAt = tt.updated
+		opts := Options{Now: now}
+		if !tt.statusSince.IsZero() {
+			opts.StatusSince = map[string]time.Time{item.ID: tt.statusSince}
+		}
+
+		got := make(map[string]float64)
+		for _, c := range Evaluate(ActiveRules(), item, opts).Contributions {
+			if c.Category == CategoryRecency || c.Category == CategoryWaiting {
+				got[c.RuleID] = c.Points
+			}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: contributions = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for id, points := range tt.want {
+			if got[id] != points {
+				t.Errorf("%s: %s = %v, want %v", tt.name, id, got[id], points)
+			}
+		}
+	}
+}
+
+func TestEvaluatePatientsCap(t *testing.T) {
+	tests := []struct {
+		name     string
+		cap      int
+		patients int
+		want     float64
+	}{
+		{"under the cap", 10, 3, 6},
+		{"over the cap", 10, 15, 20},
+		{"no cap set", 0, 15, 30},
+	}
+	for _, tt := range tests {
+		rules := *ActiveRules()
+		rules.Patients = PatientRule{PointsPerPerson: 2, Cap: tt.cap}
+		var item services.DataItem
+		item.Location.Properties.Patient = tt.patients
+
+		var got float64
+		for _, c := range Evaluate(&rules, item, Options{}).Contributions {
+			if c.Category == CategoryPatients {
+				got = c.Points
+			}
+		}
+		if got != tt.want {
+			t.Errorf("%s: patients points = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
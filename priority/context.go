@@ -0,0 +1,204 @@
+package priority
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Nxdus/hatyai-api/textnorm"
+)
+
+// ContextRule lists the words that, near a matched keyword, mean the
+// keyword does not describe the current situation: "ไม่ได้หมดสติ" (not
+// unconscious), "เคยชัก" (had a seizure before), "ชักแต่หายแล้ว" (recovered).
+// Window is the number of words looked at on each side of the keyword;
+// filler words such as "มี" or "อาการ" are skipped without using it up.
+type ContextRule struct {
+	Window    int      `json:"window"`
+	Negations []string `json:"negations"`
+	Past      []string `json:"past"`
+	Resolved  []string `json:"resolved"`
+	Fillers   []string `json:"fillers"`
+
+	negations, past, resolved, fillers map[string]struct{}
+}
+
+type mention int
+
+const (
+	mentionActive mention = iota
+	mentionNegated
+	mentionPast
+	mentionResolved
+)
+
+var mentionStatus = map[mention]string{
+	mentionNegated:  StatusNegated,
+	mentionPast:     StatusPast,
+	mentionResolved: StatusResolved,
+}
+
+func (c *ContextRule) prepare() {
+	c.negations = wordSet(c.Negations)
+	c.past = wordSet(c.Past)
+	c.resolved = wordSet(c.Resolved)
+	c.fillers = wordSet(c.Fillers)
+}
+
+// words lists every context word, for the segmentation dictionary.
+func (c *ContextRule) words() []string {
+	words := make([]string, 0, len(c.Negations)+len(c.Past)+len(c.Resolved)+len(c.Fillers))
+	words = append(words, c.Negations...)
+	words = append(words, c.Past...)
+	words = append(words, c.Resolved...)
+	return append(words, c.Fillers...)
+}
+
+func wordSet(words []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		if w = textnorm.Compact(w); w != "" {
+			set[w] = struct{}{}
+		}
+	}
+	return set
+}
+
+// classify looks at the words around text[start:end]. Negation and past
+// markers count before the keyword, resolution markers after it.
+func (c *ContextRule) classify(tokens []textnorm.Token, start, end int) mention {
+	if c.Window <= 0 {
+		return mentionActive
+	}
+
+	seen := 0
+	for i := len(tokens) - 1; i >= 0 && seen < c.Window; i-- {
+		w := tokens[i].Text
+		if tokens[i].End > start {
+			continue
+		}
+		if _, ok := c.negations[w]; ok {
+			return mentionNegated
+		}
+		if _, ok := c.past[w]; ok {
+			return mentionPast
+		}
+		if _, ok := c.fillers[w]; !ok {
+			seen++
+		}
+	}
+
+	seen = 0
+	for _, tok := range tokens {
+		if tok.Start < end {
+			continue
+		}
+		if _, ok := c.resolved[tok.Text]; ok {
+			return mentionResolved
+		}
+		if _, ok := c.fillers[tok.Text]; !ok {
+			if seen++; seen >= c.Window {
+				break
+			}
+		}
+	}
+	return mentionActive
+}
+
+// keywordHit is a keyword mention that counts towards the score. start and
+// end are byte offsets of its first counted mention in the compacted text.
+type keywordHit struct {
+	tier       *KeywordTier
+	keyword    Keyword
+	points     float64
+	start, end int
+}
+
+// hits returns every keyword of the tier with a mention that counts, plus
+// zero-point contributions for keywords only found in a discounted context.
+// text must be compacted; tokens are its segmentation with dict and are
+// computed on first use.
+func (t *KeywordTier) hits(text string, tokens *[]textnorm.Token, dict *textnorm.Dictionary, ctx *ContextRule, category string) ([]keywordHit, []Contribution) {
+	if text == "" {
+		return nil, nil
+	}
+
+	var found []keywordHit
+	var discounted []Contribution
+	for _, kw := range t.Keywords {
+		if kw.compact == "" || !strings.Contains(text, kw.compact) {
+			continue
+		}
+		if *tokens == nil {
+			*tokens = dict.Segment(text)
+		}
+
+		last, counted := mentionActive, false
+		for from := 0; ; {
+			idx := strings.Index(text[from:], kw.compact)
+			if idx < 0 {
+				break
+			}
+			start := from + idx
+			end := start + len(kw.compact)
+			if last = ctx.classify(*tokens, start, end); last == mentionActive {
+				found = append(found, keywordHit{tier: t, keyword: kw, points: t.points(kw), start: start, end: end})
+				counted = true
+				break
+			}
+			from = end
+		}
+		if !counted {
+			discounted = append(discounted, Contribution{
+				RuleID:   t.ruleID(category),
+				Category: category,
+				Value:    kw.Term,
+				Status:   mentionStatus[last],
+				labels:   [2]string{t.Label, t.LabelEN},
+			})
+		}
+	}
+	return found, discounted
+}
+
+// distinctHits keeps one hit per signal. A keyword found inside a longer
+// matched keyword ("หัวใจ" inside "หัวใจหยุด") and a keyword listed in more
+// than one tier are only counted once, keeping the higher-scoring hit.
+func distinctHits(hits []keywordHit) []keywordHit {
+	sorted := append([]keywordHit(nil), hits...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		li, lj := sorted[i].end-sorted[i].start, sorted[j].end-sorted[j].start
+		if li != lj {
+			return li > lj
+		}
+		return sorted[i].points > sorted[j].points
+	})
+
+	kept := make([]keywordHit, 0, len(sorted))
+	for _, h := range sorted {
+		covered := false
+		for _, k := range kept {
+			if h.start >= k.start && h.end <= k.end {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			kept = append(kept, h)
+		}
+	}
+
+	// Restore rule-file order so reasons read the same way the tiers do.
+	order := make(map[*KeywordTier]int)
+	for i, h := range hits {
+		if _, ok := order[h.tier]; !ok {
+			order[h.tier] = i
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		if kept[i].tier != kept[j].tier {
+			return order[kept[i].tier] < order[kept[j].tier]
+		}
+		return kept[i].start < kept[j].start
+	})
+	return kept
+}
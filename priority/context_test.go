@@ -0,0 +1,47 @@
+package priority
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Nxdus/hatyai-api/textnorm"
+)
+
+func TestContextClassify(t *testing.T) {
+	rules := ActiveRules()
+	tests := []struct {
+		text, keyword string
+		want          mention
+	}{
+		{"หมดสติ", "หมดสติ", mentionActive},
+		{"ไม่ได้หมดสติ", "หมดสติ", mentionNegated},
+		{"ไม่ ได้ หมด สติ", "หมดสติ", mentionNegated},
+		{"ไม่มีอาการหอบ", "หอบ", mentionNegated},
+		{"ไม่สบาย หอบ", "หอบ", mentionActive},
+		{"เคยชัก", "ชัก", mentionPast},
+		{"เมื่อวานชัก", "ชัก", mentionPast},
+		{"ชักแต่หายแล้ว", "ชัก", mentionResolved},
+		{"หอบ ไม่ได้นอน", "หอบ", mentionActive},
+	}
+	for _, tt := range tests {
+		text := textnorm.Compact(tt.text)
+		start := strings.Index(text, textnorm.Compact(tt.keyword))
+		if start < 0 {
+			t.Fatalf("%q does not contain %q", tt.text, tt.keyword)
+		}
+		end := start + len(textnorm.Compact(tt.keyword))
+		got := rules.Context.classify(rules.dict.Segment(text), start, end)
+		if got != tt.want {
+			t.Errorf("classify(%q, %q) = %v, want %v", tt.text, tt.keyword, got, tt.want)
+		}
+	}
+}
+
+func TestContextClassifyDisabled(t *testing.T) {
+	var ctx ContextRule
+	text := textnorm.Compact("ไม่ได้หมดสติ")
+	start := strings.Index(text, "หมดสติ")
+	if got := ctx.classify(textnorm.Segment(text), start, len(text)); got != mentionActive {
+		t.Errorf("a zero window should not discount mentions, got %v", got)
+	}
+}
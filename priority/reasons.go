@@ -0,0 +1,103 @@
+package priority
+
+import "strings"
+
+const (
+	LangTH = "th"
+	LangEN = "en"
+)
+
+const (
+	CategorySickLevel     = "sick_level"
+	CategoryPatients      = "patients"
+	CategoryAge           = "age"
+	CategoryVulnerability = "vulnerability"
+	CategoryDisease       = "disease"
+	CategoryKeyword       = "keyword"
+	CategoryRecency       = "recency"
+	CategoryWaiting       = "waiting"
+)
+
+// Statuses of a contribution that was matched but did not add points.
+const (
+	StatusCapped   = "capped"
+	StatusCounted  = "counted"
+	StatusNegated  = "negated"
+	StatusPast     = "past"
+	StatusResolved = "resolved"
+)
+
+// Contribution is one rule's effect on a score. RuleID names the rule in
+// the rule file (e.g. "sick_level.3", "keyword.urgent",
+// "age_band.elderly"), Value is what it matched and Text is the rendered
+// reason.
+type Contribution struct {
+	RuleID   string  `json:"rule_id"`
+	Category string  `json:"category"`
+	Value    string  `json:"value"`
+	Points   float64 `json:"points"`
+	Status   string  `json:"status,omitempty"`
+	Text     string  `json:"text"`
+
+	labels [2]string // Thai and English label from the rule file
+}
+
+// ParseLang maps a lang query value to a supported language, defaulting to
+// Thai when val is empty.
+func ParseLang(val string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(val)) {
+	case "", LangTH:
+		return LangTH, true
+	case LangEN:
+		return LangEN, true
+	}
+	return "", false
+}
+
+var statusText = map[string][2]string{
+	StatusCounted:  {"นับจากผู้ประสบภัยแล้ว", "already counted for victims"},
+	StatusNegated:  {"ปฏิเสธ", "negated"},
+	StatusPast:     {"เหตุการณ์ในอดีต", "past event"},
+	StatusResolved: {"หายแล้ว", "resolved"},
+}
+
+func (c *Contribution) render(lang string) string {
+	en := lang == LangEN
+	pick := func(th, eng string) string {
+		if en {
+			return eng
+		}
+		return th
+	}
+	label := c.labels[0]
+	if en && c.labels[1] != "" {
+		label = c.labels[1]
+	}
+
+	if st, ok := statusText[c.Status]; ok {
+		return pick("ไม่นับคีย์เวิร์ด ("+st[0]+"): ", "Keyword not counted ("+st[1]+"): ") + c.Value
+	}
+
+	var text string
+	switch c.Category {
+	case CategorySickLevel:
+		text = pick("ระดับความเจ็บป่วย: ", "Sick level: ") + c.Value
+	case CategoryPatients:
+		text = pick("มีผู้ป่วยจำนวน "+c.Value+" คน", c.Value+" patients")
+	case CategoryAge:
+		text = label + ": " + c.Value + pick(" ปี", " years")
+	case CategoryVulnerability:
+		text = label + ": " + c.Value + pick(" คน", " people")
+	case CategoryRecency:
+		text = label
+	case CategoryWaiting:
+		text = label + ": " + c.Value + pick(" ชั่วโมง", " hours")
+	default:
+		text = label + ": " + c.Value
+	}
+
+	if c.Status == StatusCapped {
+		text += pick(" (เกินเพดานคะแนน)", " (over score cap)")
+	}
+	return text
+}
@@ -0,0 +1,553 @@
+package priority
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Nxdus/hatyai-api/filewatch"
+	"github.com/Nxdus/hatyai-api/textnorm"
+)
+
+//go:embed rules/default.json
+var defaultRulesJSON []byte
+
+// RuleSet is the versioned rule file: one or more named scoring profiles,
+// one of which is used when a request does not ask for a profile.
+type RuleSet struct {
+	Version        string            `json:"version"`
+	DefaultProfile string            `json:"default_profile"`
+	Profiles       map[string]*Rules `json:"-"`
+}
+
+// Rules is a single scoring profile. Version and Profile are filled in from
+// the rule set it was loaded from.
+type Rules struct {
+	Version          string             `json:"-"`
+	Profile          string             `json:"-"`
+	SickLevels       map[string]float64 `json:"sick_levels"`
+	Patients         PatientRule        `json:"patients"`
+	AgeBands         []AgeBand          `json:"age_bands"`
+	Vulnerabilities  []Vulnerability    `json:"vulnerabilities"`
+	VulnerabilityCap float64            `json:"vulnerability_cap"`
+	Disease          KeywordTier        `json:"disease"`
+	KeywordTiers     []KeywordTier      `json:"keyword_tiers"`
+	KeywordCap       float64            `json:"keyword_cap"`
+	Recency          []RecencyRule      `json:"recency"`
+	Waiting          WaitingRule        `json:"waiting"`
+	Context          ContextRule        `json:"context"`
+	Levels           []LevelCutoff      `json:"levels"`
+
+	dict *textnorm.Dictionary
+}
+
+// PatientRule scores each patient or victim, counting at most Cap people
+// when Cap is set.
+type PatientRule struct {
+	PointsPerPerson float64 `json:"points_per_person"`
+	Cap             int     `json:"cap"`
+}
+
+// AgeBand matches ages in [Min, Max). A zero Max leaves the band open-ended.
+type AgeBand struct {
+	ID      string  `json:"id,omitempty"`
+	Label   string  `json:"label"`
+	LabelEN string  `json:"label_en,omitempty"`
+	Min     int     `json:"min"`
+	Max     int     `json:"max"`
+	Points  float64 `json:"points"`
+}
+
+// Vulnerability is a group of people who need help first, such as infants
+// or the bedridden. A victim belongs to it when their age is in [MinAge,
+// MaxAge) years, or when their condition or medical needs mention one of
+// Keywords. Each member adds Points, up to Cap when Cap is set.
+type Vulnerability struct {
+	ID       string   `json:"id"`
+	Label    string   `json:"label"`
+	LabelEN  string   `json:"label_en,omitempty"`
+	MinAge   float64  `json:"min_age,omitempty"`
+	MaxAge   float64  `json:"max_age,omitempty"`
+	Keywords []string `json:"keywords"`
+	Points   float64  `json:"points"`
+	Cap      float64  `json:"cap,omitempty"`
+
+	keywords []string
+}
+
+// KeywordTier scores every distinct keyword found in a text field. Each
+// keyword earns its own points, or the tier's points when it has none, and
+// the tier total is limited to Cap when Cap is set.
+type KeywordTier struct {
+	ID       string    `json:"id"`
+	Label    string    `json:"label"`
+	LabelEN  string    `json:"label_en,omitempty"`
+	Points   float64   `json:"points"`
+	Cap      float64   `json:"cap,omitempty"`
+	Keywords []Keyword `json:"keywords"`
+}
+
+// Keyword is written in the rule file either as a plain string, scored at
+// the tier's points, or as {"term": "...", "points": n}.
+type Keyword struct {
+	Term   string  `json:"term"`
+	Points float64 `json:"points,omitempty"`
+
+	compact string
+}
+
+func (k *Keyword) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		k.Points = 0
+		return json.Unmarshal(data, &k.Term)
+	}
+	type plain Keyword
+	var v plain
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	*k = Keyword(v)
+	return nil
+}
+
+func (k Keyword) MarshalJSON() ([]byte, error) {
+	if k.Points == 0 {
+		return json.Marshal(k.Term)
+	}
+	type plain Keyword
+	return json.Marshal(plain(k))
+}
+
+func (t *KeywordTier) points(k Keyword) float64 {
+	if k.Points != 0 {
+		return k.Points
+	}
+	return t.Points
+}
+
+// RecencyRule matches when the hours since updated_at are above MinHours and
+// at most MaxHours. A zero bound is ignored.
+type RecencyRule struct {
+	ID       string  `json:"id,omitempty"`
+	Label    string  `json:"label"`
+	LabelEN  string  `json:"label_en,omitempty"`
+	MinHours float64 `json:"min_hours"`
+	MaxHours float64 `json:"max_hours"`
+	Points   float64 `json:"points"`
+}
+
+// WaitingRule escalates requests that are still waiting for help. It
+// applies while status_text is one of Statuses and adds the Curve's points
+// at the number of hours the request has had that status: linear between
+// curve points, 0 before the first and flat after the last. Negative
+// recency rules are not applied to a waiting request, since no update there
+// means nobody has answered, not that the need has passed.
+type WaitingRule struct {
+	ID       string       `json:"id,omitempty"`
+	Label    string       `json:"label"`
+	LabelEN  string       `json:"label_en,omitempty"`
+	Statuses []string     `json:"statuses"`
+	Curve    []CurvePoint `json:"curve"`
+
+	statuses map[string]struct{}
+}
+
+type CurvePoint struct {
+	Hours  float64 `json:"hours"`
+	Points float64 `json:"points"`
+}
+
+type LevelCutoff struct {
+	Name     string  `json:"name"`
+	MinScore float64 `json:"min_score"`
+}
+
+type ruleSetFile struct {
+	Version        string                     `json:"version"`
+	DefaultProfile string                     `json:"default_profile"`
+	Profiles       map[string]json.RawMessage `json:"profiles"`
+}
+
+var activeRuleSet atomic.Pointer[RuleSet]
+
+func init() {
+	rs, err := ParseRuleSet(defaultRulesJSON)
+	if err != nil {
+		panic("priority: invalid embedded rules: " + err.Error())
+	}
+	activeRuleSet.Store(rs)
+}
+
+// ActiveRuleSet returns the rule set currently loaded.
+func ActiveRuleSet() *RuleSet {
+	return activeRuleSet.Load()
+}
+
+// ActiveRules returns the default profile of the active rule set, which is
+// what Calculate uses.
+func ActiveRules() *Rules {
+	rules, _ := ActiveRuleSet().Profile("")
+	return rules
+}
+
+// Profile returns the named profile, or the default profile for "".
+func (rs *RuleSet) Profile(name string) (*Rules, bool) {
+	if name == "" {
+		name = rs.DefaultProfile
+	}
+	rules, ok := rs.Profiles[name]
+	return rules, ok
+}
+
+func (rs *RuleSet) ProfileNames() []string {
+	names := make([]string, 0, len(rs.Profiles))
+	for name := range rs.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseRuleSet decodes and validates a rule file. A profile may name another
+// profile in "extends"; its own fields then replace the inherited ones, so
+// only the weights that differ need to be written out.
+func ParseRuleSet(data []byte) (*RuleSet, error) {
+	var file ruleSetFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if file.Version == "" {
+		return nil, errors.New("rules: version is required")
+	}
+	if len(file.Profiles) == 0 {
+		return nil, errors.New("rules: at least one profile is required")
+	}
+	if file.DefaultProfile == "" {
+		return nil, errors.New("rules: default_profile is required")
+	}
+	if _, ok := file.Profiles[file.DefaultProfile]; !ok {
+		return nil, fmt.Errorf("rules: default_profile %q is not defined", file.DefaultProfile)
+	}
+
+	rs := &RuleSet{
+		Version:        file.Version,
+		DefaultProfile: file.DefaultProfile,
+		Profiles:       make(map[string]*Rules, len(file.Profiles)),
+	}
+	for name := range file.Profiles {
+		if _, err := resolveProfile(&file, rs, name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return rs, nil
+}
+
+func resolveProfile(file *ruleSetFile, rs *RuleSet, name string, visiting []string) (*Rules, error) {
+	if rules, ok := rs.Profiles[name]; ok {
+		return rules, nil
+	}
+	for _, v := range visiting {
+		if v == name {
+			return nil, fmt.Errorf("rules: profile %q extends itself", name)
+		}
+	}
+	raw, ok := file.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("rules: profile %q is not defined", name)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("rules: profile %q: %w", name, err)
+	}
+
+	rules := &Rules{}
+	if extends, ok := fields["extends"]; ok {
+		var base string
+		if err := json.Unmarshal(extends, &base); err != nil {
+			return nil, fmt.Errorf("rules: profile %q: extends must be a profile name", name)
+		}
+		parent, err := resolveProfile(file, rs, base, append(visiting, name))
+		if err != nil {
+			return nil, err
+		}
+		rules = parent.inherit(fields)
+	}
+
+	if err := rules.apply(raw, file.Version, name); err != nil {
+		return nil, fmt.Errorf("profile %q: %w", name, err)
+	}
+	rs.Profiles[name] = rules
+	return rules, nil
+}
+
+// Override returns a copy of r with the fields in overlay replaced, using
+// the same rules as a profile that extends r. The result is validated and
+// tagged with version so it is never mistaken for a loaded rule set.
+func (r *Rules) Override(overlay json.RawMessage, version string) (*Rules, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(overlay, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "extends")
+	rules := r.inherit(fields)
+	if err := rules.apply(overlay, version, r.Profile); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// apply decodes raw over r, then validates and prepares the result.
+func (r *Rules) apply(raw json.RawMessage, version, profile string) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&struct {
+		*Rules
+		Extends string `json:"extends"`
+	}{Rules: r}); err != nil {
+		return err
+	}
+
+	r.Version = version
+	r.Profile = profile
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	sort.SliceStable(r.Levels, func(i, j int) bool {
+		return r.Levels[i].MinScore > r.Levels[j].MinScore
+	})
+	r.prepare()
+	return nil
+}
+
+func LoadRuleSet(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRuleSet(data)
+}
+
+// inherit copies r as the base for a profile overlay. Fields the overlay
+// sets are cleared first so they replace the inherited value instead of
+// being merged into it; only sick_levels is merged key by key.
+func (r *Rules) inherit(overlay map[string]json.RawMessage) *Rules {
+	c := &Rules{
+		SickLevels:       make(map[string]float64, len(r.SickLevels)),
+		Patients:         r.Patients,
+		AgeBands:         append([]AgeBand(nil), r.AgeBands...),
+		Vulnerabilities:  append([]Vulnerability(nil), r.Vulnerabilities...),
+		VulnerabilityCap: r.VulnerabilityCap,
+		Disease:          r.Disease,
+		KeywordTiers:     append([]KeywordTier(nil), r.KeywordTiers...),
+		Recency:          append([]RecencyRule(nil), r.Recency...),
+		Waiting:          r.Waiting,
+		Context:          r.Context,
+		Levels:           append([]LevelCutoff(nil), r.Levels...),
+	}
+	for k, v := range r.SickLevels {
+		c.SickLevels[k] = v
+	}
+
+	if _, ok := overlay["patients"]; ok {
+		c.Patients = PatientRule{}
+	}
+	if _, ok := overlay["age_bands"]; ok {
+		c.AgeBands = nil
+	}
+	if _, ok := overlay["vulnerabilities"]; ok {
+		c.Vulnerabilities = nil
+	}
+	if _, ok := overlay["disease"]; ok {
+		c.Disease = KeywordTier{}
+	}
+	if _, ok := overlay["keyword_tiers"]; ok {
+		c.KeywordTiers = nil
+	}
+	if _, ok := overlay["recency"]; ok {
+		c.Recency = nil
+	}
+	if _, ok := overlay["waiting"]; ok {
+		c.Waiting = WaitingRule{}
+	}
+	if _, ok := overlay["context"]; ok {
+		c.Context = ContextRule{}
+	}
+	if _, ok := overlay["levels"]; ok {
+		c.Levels = nil
+	}
+	return c
+}
+
+// prepare normalises keywords once per load and builds the profile's own
+// segmentation dictionary, so its keywords and context words are tokenised
+// as whole words without touching the dictionary other rules use.
+func (r *Rules) prepare() {
+	words := r.Context.words()
+	r.Disease.prepare()
+	words = append(words, r.Disease.terms()...)
+	r.Context.prepare()
+	r.Waiting.statuses = wordSet(r.Waiting.Statuses)
+	for i := range r.Vulnerabilities {
+		v := &r.Vulnerabilities[i]
+		v.keywords = make([]string, 0, len(v.Keywords))
+		for _, kw := range v.Keywords {
+			if kw = textnorm.Compact(kw); kw != "" {
+				v.keywords = append(v.keywords, kw)
+			}
+		}
+		words = append(words, v.Keywords...)
+	}
+	for i := range r.KeywordTiers {
+		r.KeywordTiers[i].prepare()
+		words = append(words, r.KeywordTiers[i].terms()...)
+	}
+	r.dict = textnorm.DefaultDictionary().With(words...)
+}
+
+func (t *KeywordTier) prepare() {
+	keywords := make([]Keyword, len(t.Keywords))
+	for i, kw := range t.Keywords {
+		kw.compact = textnorm.Compact(kw.Term)
+		keywords[i] = kw
+	}
+	t.Keywords = keywords
+}
+
+func (t *KeywordTier) terms() []string {
+	terms := make([]string, len(t.Keywords))
+	for i, kw := range t.Keywords {
+		terms[i] = kw.Term
+	}
+	return terms
+}
+
+func (r *Rules) Validate() error {
+	for key := range r.SickLevels {
+		if _, err := strconv.Atoi(key); err != nil {
+			return fmt.Errorf("rules: sick_levels key %q is not a number", key)
+		}
+	}
+	if r.Patients.Cap < 0 || r.Patients.PointsPerPerson < 0 {
+		return errors.New("rules: patients cap and points_per_person must not be negative")
+	}
+	for i, band := range r.AgeBands {
+		if band.Min < 0 || (band.Max != 0 && band.Max <= band.Min) {
+			return fmt.Errorf("rules: age_bands[%d] has an empty range", i)
+		}
+	}
+	for i, v := range r.Vulnerabilities {
+		if v.ID == "" {
+			return fmt.Errorf("rules: vulnerabilities[%d] id is required", i)
+		}
+		if v.MinAge < 0 || (v.MaxAge != 0 && v.MaxAge <= v.MinAge) {
+			return fmt.Errorf("rules: vulnerabilities[%d] has an empty age range", i)
+		}
+		if v.MinAge == 0 && v.MaxAge == 0 && len(v.Keywords) == 0 {
+			return fmt.Errorf("rules: vulnerabilities[%d] needs an age range or keywords", i)
+		}
+		if v.Points < 0 || v.Cap < 0 {
+			return fmt.Errorf("rules: vulnerabilities[%d] points and cap must not be negative", i)
+		}
+	}
+	if r.VulnerabilityCap < 0 {
+		return errors.New("rules: vulnerability_cap must not be negative")
+	}
+	if len(r.Disease.Keywords) > 0 {
+		if err := r.Disease.validate("disease"); err != nil {
+			return err
+		}
+	}
+	for i, tier := range r.KeywordTiers {
+		if err := tier.validate(fmt.Sprintf("keyword_tiers[%d]", i)); err != nil {
+			return err
+		}
+	}
+	for i, rule := range r.Recency {
+		if rule.MaxHours != 0 && rule.MaxHours <= rule.MinHours {
+			return fmt.Errorf("rules: recency[%d] has an empty range", i)
+		}
+	}
+	if len(r.Waiting.Curve) > 0 && len(r.Waiting.Statuses) == 0 {
+		return errors.New("rules: waiting needs at least one status")
+	}
+	for i, p := range r.Waiting.Curve {
+		if p.Hours < 0 || (i > 0 && p.Hours <= r.Waiting.Curve[i-1].Hours) {
+			return fmt.Errorf("rules: waiting curve[%d] hours must be ascending and not negative", i)
+		}
+	}
+	if r.KeywordCap < 0 {
+		return errors.New("rules: keyword_cap must not be negative")
+	}
+	if r.Context.Window < 0 {
+		return errors.New("rules: context window must not be negative")
+	}
+	if len(r.Levels) == 0 {
+		return errors.New("rules: at least one level is required")
+	}
+	hasFloor := false
+	for i, lv := range r.Levels {
+		if lv.Name == "" {
+			return fmt.Errorf("rules: levels[%d] name is required", i)
+		}
+		if lv.Name != strings.ToLower(lv.Name) {
+			return fmt.Errorf("rules: levels[%d] name %q must be lower case", i, lv.Name)
+		}
+		if lv.MinScore <= 0 {
+			hasFloor = true
+		}
+	}
+	if !hasFloor {
+		return errors.New("rules: one level must have min_score 0 so every score gets a level")
+	}
+	return nil
+}
+
+func (t KeywordTier) validate(path string) error {
+	if t.ID == "" {
+		return fmt.Errorf("rules: %s id is required", path)
+	}
+	if len(t.Keywords) == 0 {
+		return fmt.Errorf("rules: %s needs at least one keyword", path)
+	}
+	if t.Cap < 0 {
+		return fmt.Errorf("rules: %s cap must not be negative", path)
+	}
+	if t.Points < 0 {
+		return fmt.Errorf("rules: %s points must not be negative", path)
+	}
+	for _, kw := range t.Keywords {
+		if kw.Term == "" {
+			return fmt.Errorf("rules: %s has an empty keyword", path)
+		}
+		if kw.Points < 0 {
+			return fmt.Errorf("rules: %s keyword %q points must not be negative", path, kw.Term)
+		}
+	}
+	return nil
+}
+
+// WatchRules loads the rule set from path and polls it for changes. A file
+// that fails to load or validate is logged and the previous rules stay active.
+func WatchRules(path string, interval time.Duration) error {
+	return filewatch.Watch(path, interval, func() error {
+		rs, err := LoadRuleSet(path)
+		if err != nil {
+			return err
+		}
+		activeRuleSet.Store(rs)
+		log.Printf("priority rules loaded (version=%s, profiles=%v, file=%s)", rs.Version, rs.ProfileNames(), path)
+		return nil
+	})
+}
@@ -0,0 +1,125 @@
+package priority
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Nxdus/hatyai-api/textnorm"
+)
+
+func TestOverrideKeepsDictionaryLocal(t *testing.T) {
+	active := ActiveRules()
+	overlay := json.RawMessage(`{"keyword_tiers": [{"id": "probe", "label": "probe", "points": 1, "keywords": ["ซอยพิเศษ"]}]}`)
+	candidate, err := active.Override(overlay, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	known := func(tokens []textnorm.Token) bool {
+		return len(tokens) == 1 && tokens[0].Known
+	}
+	if !known(candidate.dict.Segment("ซอยพิเศษ")) {
+		t.Errorf("candidate dictionary does not know its own keyword")
+	}
+	if known(active.dict.Segment("ซอยพิเศษ")) {
+		t.Errorf("active rules learned a keyword from a candidate")
+	}
+	if known(textnorm.Segment("ซอยพิเศษ")) {
+		t.Errorf("built-in dictionary learned a keyword from a candidate")
+	}
+}
+
+const extendsRuleSet = `{
+  "version": "test",
+  "default_profile": "base",
+  "profiles": {
+    "base": {
+      "sick_levels": {"1": 10, "2": 20},
+      "patients": {"points_per_person": 2, "cap": 5},
+      "keyword_tiers": [{"id": "urgent", "label": "ด่วน", "points": 10, "keywords": ["หมดสติ"]}],
+      "levels": [{"name": "high", "min_score": 50}, {"name": "low", "min_score": 0}]
+    },
+    "child": {
+      "extends": "base",
+      "sick_levels": {"2": 30, "3": 40},
+      "keyword_tiers": [{"id": "boat", "label": "เรือ", "points": 5, "keywords": ["ต้องการเรือ"]}]
+    },
+    "grandchild": {
+      "extends": "child",
+      "patients": {"points_per_person": 1}
+    }
+  }
+}`
+
+func TestParseRuleSetExtends(t *testing.T) {
+	rs, err := ParseRuleSet([]byte(extendsRuleSet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := rs.Profile("")
+	child, _ := rs.Profile("child")
+	grandchild, _ := rs.Profile("grandchild")
+
+	want := map[string]float64{"1": 10, "2": 30, "3": 40}
+	for k, v := range want {
+		if child.SickLevels[k] != v {
+			t.Errorf("child sick_levels[%s] = %v, want %v", k, child.SickLevels[k], v)
+		}
+	}
+	if base.SickLevels["2"] != 20 || len(base.SickLevels) != 2 {
+		t.Errorf("extending changed the base sick_levels: %v", base.SickLevels)
+	}
+
+	if len(child.KeywordTiers) != 1 || child.KeywordTiers[0].ID != "boat" {
+		t.Errorf("child keyword_tiers should replace the base tiers, got %+v", child.KeywordTiers)
+	}
+	if child.Patients != base.Patients {
+		t.Errorf("child should inherit patients, got %+v", child.Patients)
+	}
+	if len(child.Levels) != 2 || child.Levels[0].Name != "high" {
+		t.Errorf("child should inherit levels, got %+v", child.Levels)
+	}
+
+	if grandchild.Patients != (PatientRule{PointsPerPerson: 1}) {
+		t.Errorf("grandchild patients should replace the inherited rule whole, got %+v", grandchild.Patients)
+	}
+	if grandchild.SickLevels["3"] != 40 || grandchild.KeywordTiers[0].ID != "boat" {
+		t.Errorf("grandchild should inherit through child, got %v %+v", grandchild.SickLevels, grandchild.KeywordTiers)
+	}
+	if grandchild.Version != "test" || grandchild.Profile != "grandchild" {
+		t.Errorf("grandchild version/profile = %q/%q", grandchild.Version, grandchild.Profile)
+	}
+}
+
+func TestParseRuleSetExtendsErrors(t *testing.T) {
+	tests := map[string]string{
+		"cycle": `{"version": "t", "default_profile": "a", "profiles": {
+			"a": {"extends": "b"}, "b": {"extends": "a"}}}`,
+		"missing parent": `{"version": "t", "default_profile": "a", "profiles": {
+			"a": {"extends": "nope"}}}`,
+		"extends not a name": `{"version": "t", "default_profile": "a", "profiles": {
+			"a": {"levels": [{"name": "low", "min_score": 0}]}, "b": {"extends": 1}}}`,
+	}
+	for name, data := range tests {
+		if _, err := ParseRuleSet([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestOverrideLevelNames(t *testing.T) {
+	tests := []struct {
+		levels string
+		ok     bool
+	}{
+		{`[{"name": "urgent", "min_score": 50}, {"name": "routine", "min_score": 0}]`, true},
+		{`[{"name": "Urgent", "min_score": 50}, {"name": "routine", "min_score": 0}]`, false},
+		{`[{"name": "urgent", "min_score": 50}]`, false},
+	}
+	for _, tt := range tests {
+		_, err := ActiveRules().Override(json.RawMessage(`{"levels": `+tt.levels+`}`), "test")
+		if (err == nil) != tt.ok {
+			t.Errorf("levels %s: err = %v, want ok %v", tt.levels, err, tt.ok)
+		}
+	}
+}
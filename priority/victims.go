@@ -0,0 +1,148 @@
+package priority
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Nxdus/hatyai-api/services"
+	"github.com/Nxdus/hatyai-api/textnorm"
+)
+
+// scoreVictims counts the victims in each vulnerable group and adds one
+// contribution per group found. Groups past VulnerabilityCap are kept with
+// zero points and status "capped". banded is the index of the victim whose
+// age already earned an age band, or -1; they are not counted again in a
+// group they belong to by age. It also returns the groups found.
+func (r *Rules) scoreVictims(victims []services.Victim, banded int) ([]Contribution, []*Vulnerability) {
+	counts := make([]int, len(r.Vulnerabilities))
+	for i, v := range victims {
+		age, ok := victimAge(v)
+		text := textnorm.Compact(v.Condition + " " + strings.Join(v.MedicalNeeds, " "))
+		for g := range r.Vulnerabilities {
+			group := &r.Vulnerabilities[g]
+			if i == banded && ok && group.matchesAge(age) {
+				continue
+			}
+			if (ok && group.matchesAge(age)) || group.matchesText(text) {
+				counts[g]++
+			}
+		}
+	}
+
+	var contribs []Contribution
+	var found []*Vulnerability
+	var total float64
+	for i, n := range counts {
+		if n == 0 {
+			continue
+		}
+		group := &r.Vulnerabilities[i]
+		found = append(found, group)
+		points := float64(n) * group.Points
+		if group.Cap > 0 {
+			points = math.Min(points, group.Cap)
+		}
+		if r.VulnerabilityCap > 0 {
+			points = math.Min(points, r.VulnerabilityCap-total)
+		}
+
+		c := Contribution{
+			RuleID:   "vulnerability." + group.ID,
+			Category: CategoryVulnerability,
+			Value:    strconv.Itoa(n),
+			labels:   [2]string{group.Label, group.LabelEN},
+		}
+		if points <= 0 {
+			c.Status = StatusCapped
+		} else {
+			c.Points = points
+			total += points
+		}
+		contribs = append(contribs, c)
+	}
+	return contribs, found
+}
+
+func (g *Vulnerability) matchesAge(age float64) bool {
+	return (g.MinAge > 0 || g.MaxAge > 0) && age >= g.MinAge && (g.MaxAge == 0 || age < g.MaxAge)
+}
+
+func (g *Vulnerability) matchesText(text string) bool {
+	if text == "" {
+		return false
+	}
+	for _, kw := range g.keywords {
+		if strings.Contains(text, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// describes reports whether a keyword names this group, so a request that
+// mentions it in other is talking about people its victims already count.
+func (g *Vulnerability) describes(k Keyword) bool {
+	for _, kw := range g.keywords {
+		if strings.Contains(k.compact, kw) || strings.Contains(kw, k.compact) {
+			return true
+		}
+	}
+	return false
+}
+
+// victimAges returns the readable age of every victim that has one, with
+// the index of the victim it belongs to.
+func victimAges(victims []services.Victim) ([]float64, []int) {
+	var ages []float64
+	var owners []int
+	for i, v := range victims {
+		if age, ok := victimAge(v); ok {
+			ages = append(ages, age)
+			owners = append(owners, i)
+		}
+	}
+	return ages, owners
+}
+
+// countedHits takes out the keyword hits that name a vulnerable group
+// already scored from the victims, returning them as zero-point
+// contributions with status "counted".
+func countedHits(hits []keywordHit, groups []*Vulnerability) ([]keywordHit, []Contribution) {
+	if len(groups) == 0 {
+		return hits, nil
+	}
+	kept := hits[:0:0]
+	var counted []Contribution
+	for _, h := range hits {
+		named := false
+		for _, g := range groups {
+			if g.describes(h.keyword) {
+				named = true
+				break
+			}
+		}
+		if !named {
+			kept = append(kept, h)
+			continue
+		}
+		counted = append(counted, Contribution{
+			RuleID:   h.tier.ruleID(CategoryKeyword),
+			Category: CategoryKeyword,
+			Value:    h.keyword.Term,
+			Status:   StatusCounted,
+			labels:   [2]string{h.tier.Label, h.tier.LabelEN},
+		})
+	}
+	return kept, counted
+}
+
+// victimAge returns a victim's age in years, the first one if several are
+// written.
+func victimAge(v services.Victim) (float64, bool) {
+	ages := ParseAges(v.Age)
+	if len(ages) == 0 {
+		return 0, false
+	}
+	return ages[0], true
+}
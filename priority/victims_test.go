@@ -0,0 +1,74 @@
+package priority
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Nxdus/hatyai-api/services"
+)
+
+func TestEvaluateVulnerableOnce(t *testing.T) {
+	tests := []struct {
+		name    string
+		other   string
+		ages    string
+		victims []services.Victim
+		want    map[string]float64 // rule_id to points, for age, vulnerability and keyword
+	}{
+		{
+			"elderly victim",
+			"", "",
+			[]services.Victim{{Age: "80"}},
+			map[string]float64{"age_band.elderly": 8},
+		},
+		{
+			"two elderly victims",
+			"", "",
+			[]services.Victim{{Age: "80"}, {Age: "75"}},
+			map[string]float64{"age_band.elderly": 8, "vulnerability.elderly": 4},
+		},
+		{
+			"elderly from the ages field",
+			"", "80",
+			nil,
+			map[string]float64{"age_band.elderly": 8},
+		},
+		{
+			"bedridden victim named in other",
+			"มีผู้ป่วยติดเตียง", "",
+			[]services.Victim{{Age: "50", Condition: "ติดเตียง"}},
+			map[string]float64{"vulnerability.bedridden": 8, "keyword.vulnerable": 0},
+		},
+		{
+			"pregnant victim named in other",
+			"คนใกล้คลอด ขาดน้ำ", "",
+			[]services.Victim{{Age: "30", Condition: "ใกล้คลอด"}},
+			map[string]float64{"vulnerability.pregnant": 8, "keyword.vulnerable": 0, "keyword.assistance": 5},
+		},
+		{
+			"bedridden in other only",
+			"มีผู้ป่วยติดเตียง", "",
+			nil,
+			map[string]float64{"keyword.vulnerable": 8},
+		},
+	}
+	for _, tt := range tests {
+		var item services.DataItem
+		item.Location.Properties.Other = tt.other
+		item.Location.Properties.Ages = tt.ages
+		item.Location.Properties.Victims = tt.victims
+
+		got := make(map[string]float64)
+		res := Evaluate(ActiveRules(), item, Options{Now: time.Date(2025, 11, 28, 12, 0, 0, 0, time.UTC)})
+		for _, c := range res.Contributions {
+			switch c.Category {
+			case CategoryAge, CategoryVulnerability, CategoryKeyword:
+				got[c.RuleID] = c.Points
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: contributions = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Nxdus/hatyai-api/areas"
+	"github.com/Nxdus/hatyai-api/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// areaFilter selects the items a response covers, from the region,
+// province, district, subdistrict and bbox query parameters. Parameters
+// combine; with none of them the filter is defaultRegion, which may be
+// empty for no filter.
+type areaFilter struct {
+	Region      string    `json:"region,omitempty"`
+	Province    string    `json:"province,omitempty"`
+	District    string    `json:"district,omitempty"`
+	Subdistrict string    `json:"subdistrict,omitempty"`
+	BBox        []float64 `json:"bbox,omitempty"`
+
+	match []func(services.DataItem) bool
+}
+
+var errUnknownRegion = errors.New("unknown region")
+
+// ambiguousAreaError is returned for an area name that several areas share,
+// so a filter never silently picks one of them.
+type ambiguousAreaError struct {
+	level      areas.Level
+	name       string
+	candidates []*areas.Area
+}
+
+func (e *ambiguousAreaError) Error() string {
+	return fmt.Sprintf("%s %q is ambiguous; use its code", e.level, e.name)
+}
+
+// resolveArea resolves name within parent when the level above was given
+// and resolved, and across the gazetteer otherwise.
+func resolveArea(gz *areas.Gazetteer, parent *areas.Area, level areas.Level, name string) (*areas.Area, bool) {
+	if parent != nil {
+		return gz.ResolveUnder(parent, level, name)
+	}
+	return gz.Resolve(level, name)
+}
+
+// ambiguousArea returns an ambiguousAreaError when name did not resolve
+// because several areas share it.
+func ambiguousArea(gz *areas.Gazetteer, resolved bool, parent *areas.Area, level areas.Level, name string) error {
+	if resolved || parent != nil {
+		return nil
+	}
+	if found := gz.Candidates(level, name); len(found) > 1 {
+		return &ambiguousAreaError{level: level, name: name, candidates: found}
+	}
+	return nil
+}
+
+func parseAreaFilter(c *fiber.Ctx, defaultRegion string) (*areaFilter, error) {
+	f := &areaFilter{}
+	gz := areas.Default()
+
+	levels := []struct {
+		level areas.Level
+		query string
+		name  *string
+	}{
+		{areas.LevelProvince, "province", &f.Province},
+		{areas.LevelDistrict, "district", &f.District},
+		{areas.LevelSubdistrict, "subdistrict", &f.Subdistrict},
+	}
+	var parent *areas.Area
+	for _, lv := range levels {
+		name := strings.TrimSpace(c.Query(lv.query))
+		if name == "" {
+			continue
+		}
+		get := areaGetters[lv.level]
+		area, ok := resolveArea(gz, parent, lv.level, name)
+		if err := ambiguousArea(gz, ok, parent, lv.level, name); err != nil {
+			return nil, err
+		}
+		parent = area
+		if ok {
+			*lv.name = area.NameTH
+			f.match = append(f.match, func(item services.DataItem) bool { return gz.Matches(area, get(item)) })
+		} else {
+			*lv.name = name
+			key := areas.Key(name)
+			f.match = append(f.match, func(item services.DataItem) bool { return key != "" && areas.Key(get(item)) == key })
+		}
+	}
+
+	if q := strings.TrimSpace(c.Query("bbox")); q != "" {
+		box, err := parseBBox(q)
+		if err != nil {
+			return nil, err
+		}
+		f.BBox = box
+		f.match = append(f.match, func(item services.DataItem) bool {
+			coords := item.Location.Geometry.Coordinates
+			if len(coords) < 2 {
+				return false
+			}
+			lon, lat := coords[0], coords[1]
+			return lon >= box[0] && lat >= box[1] && lon <= box[2] && lat <= box[3]
+		})
+	}
+
+	region := strings.TrimSpace(c.Query("region"))
+	if region == "" && len(f.match) == 0 {
+		region = defaultRegion
+	}
+	switch {
+	case region == "":
+	case strings.EqualFold(region, areas.RegionAll):
+		f.Region = areas.RegionAll
+	default:
+		r, ok := areas.RegionByID(region)
+		if !ok {
+			return nil, errUnknownRegion
+		}
+		f.Region = r.ID
+		f.match = append(f.match, func(item services.DataItem) bool {
+			area, ok := gz.Resolve(areas.LevelProvince, item.Location.Properties.Province)
+			return ok && r.Contains(area.Code)
+		})
+	}
+	return f, nil
+}
+
+func (f *areaFilter) apply(items []services.DataItem) []services.DataItem {
+	return filterItems(items, f.matches)
+}
+
+func (f *areaFilter) matches(item services.DataItem) bool {
+	for _, match := range f.match {
+		if !match(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseBBox reads "min_lon,min_lat,max_lon,max_lat".
+func parseBBox(val string) ([]float64, error) {
+	parts := strings.Split(val, ",")
+	if len(parts) != 4 {
+		return nil, errors.New("bbox must be min_lon,min_lat,max_lon,max_lat")
+	}
+	box := make([]float64, 4)
+	for i, p := range parts {
+		n, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, errors.New("bbox must be min_lon,min_lat,max_lon,max_lat")
+		}
+		box[i] = n
+	}
+	if box[0] > box[2] || box[1] > box[3] {
+		return nil, errors.New("bbox minimum must not exceed maximum")
+	}
+	return box, nil
+}
+
+// areaFilterError is the 400 body for a bad area filter.
+func areaFilterError(err error) fiber.Map {
+	body := fiber.Map{"error": err.Error()}
+	if errors.Is(err, errUnknownRegion) {
+		body["regions"] = regionIDs()
+	}
+	var ambiguous *ambiguousAreaError
+	if errors.As(err, &ambiguous) {
+		body["candidates"] = ambiguous.candidates
+	}
+	return body
+}
+
+func regionIDs() []string {
+	ids := []string{areas.RegionAll}
+	for _, r := range areas.Regions() {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
@@ -0,0 +1,109 @@
+package routes
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Nxdus/hatyai-api/areas"
+	"github.com/Nxdus/hatyai-api/priority"
+	"github.com/Nxdus/hatyai-api/services"
+)
+
+type areaNode struct {
+	Name           string         `json:"name"`
+	Code           string         `json:"code,omitempty"`
+	Level          areas.Level    `json:"level"`
+	Count          int            `json:"count"`
+	PriorityLevels map[string]int `json:"priority_levels"`
+	Statuses       map[string]int `json:"status_text"`
+	Categories     map[string]int `json:"categories"`
+	Children       []*areaNode    `json:"children,omitempty"`
+
+	index map[string]*areaNode
+}
+
+// buildAreaTree nests items as province → district → subdistrict. Children
+// are keyed within their parent, so a district name shared by two provinces
+// stays as two separate nodes, and names under an area that does not
+// resolve are not resolved either. Priority levels are evaluated as of at.
+func buildAreaTree(items []services.DataItem, at time.Time) []*areaNode {
+	root := &areaNode{index: make(map[string]*areaNode)}
+	gz := areas.Default()
+	path := []areas.Level{areas.LevelProvince, areas.LevelDistrict, areas.LevelSubdistrict}
+
+	for _, item := range items {
+		level := priority.Calculate(item, at).Level
+		status := strings.TrimSpace(item.Location.Properties.StatusText)
+
+		parent := root
+		for _, lv := range path {
+			name := strings.TrimSpace(areaGetters[lv](item))
+			if name == "" {
+				break
+			}
+			node := parent.child(gz, lv, name)
+			node.Count++
+			node.PriorityLevels[level]++
+			if status != "" {
+				node.Statuses[status]++
+			}
+			for _, c := range itemCategories(item) {
+				node.Categories[string(c)]++
+			}
+			parent = node
+		}
+	}
+
+	root.sortChildren()
+	return root.Children
+}
+
+func (n *areaNode) child(gz *areas.Gazetteer, level areas.Level, name string) *areaNode {
+	key := areas.Key(name)
+	// Below a parent the gazetteer does not know, the name is kept as
+	// written: resolving it country-wide could file it under the wrong
+	// province's area.
+	var area *areas.Area
+	var resolved bool
+	switch {
+	case n.Level == "":
+		area, resolved = gz.Resolve(level, name)
+	case n.Code != "":
+		if parent, ok := gz.ByCode(n.Code); ok {
+			area, resolved = gz.ResolveUnder(parent, level, name)
+		}
+	}
+	if resolved {
+		key = "#" + area.Code
+	}
+
+	if existing, ok := n.index[key]; ok {
+		return existing
+	}
+
+	node := &areaNode{
+		Name:           name,
+		Level:          level,
+		PriorityLevels: make(map[string]int),
+		Statuses:       make(map[string]int),
+		Categories:     make(map[string]int),
+		index:          make(map[string]*areaNode),
+	}
+	if resolved {
+		node.Name = area.NameTH
+		node.Code = area.Code
+	}
+	n.index[key] = node
+	n.Children = append(n.Children, node)
+	return node
+}
+
+func (n *areaNode) sortChildren() {
+	sort.Slice(n.Children, func(i, j int) bool {
+		return strings.ToLower(n.Children[i].Name) < strings.ToLower(n.Children[j].Name)
+	})
+	for _, c := range n.Children {
+		c.sortChildren()
+	}
+}
@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nxdus/hatyai-api/services"
+)
+
+func TestBuildAreaTreeResolvesWithinParent(t *testing.T) {
+	tests := []struct {
+		name               string
+		province, district string
+		wantProvince       string
+		wantDistrict       string
+		wantDistrictCode   string
+	}{
+		{"resolved parent", "Songkhla", "Hat Yai", "สงขลา", "หาดใหญ่", "9011"},
+		{"unknown district", "สงขลา", "เมืองปัตตานี", "สงขลา", "เมืองปัตตานี", ""},
+		{"unresolved parent", "Atlantis", "Hat Yai", "Atlantis", "Hat Yai", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var item services.DataItem
+			item.Location.Properties.Province = tt.province
+			item.Location.Properties.District = tt.district
+
+			tree := buildAreaTree([]services.DataItem{item}, time.Now())
+			if len(tree) != 1 || len(tree[0].Children) != 1 {
+				t.Fatalf("want one province with one district, got %+v", tree)
+			}
+			province, district := tree[0], tree[0].Children[0]
+			if province.Name != tt.wantProvince {
+				t.Errorf("province = %q, want %q", province.Name, tt.wantProvince)
+			}
+			if district.Name != tt.wantDistrict || district.Code != tt.wantDistrictCode {
+				t.Errorf("district = %q (%q), want %q (%q)", district.Name, district.Code, tt.wantDistrict, tt.wantDistrictCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package routes
+
+import (
+	"strings"
+
+	"github.com/Nxdus/hatyai-api/category"
+	"github.com/Nxdus/hatyai-api/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// categoryFilter keeps the items with any of the categories in the
+// category query parameter, a comma-separated list. Without the parameter
+// every item is kept.
+type categoryFilter map[category.Category]bool
+
+func parseCategoryFilter(c *fiber.Ctx) (categoryFilter, error) {
+	q := strings.TrimSpace(c.Query("category"))
+	if q == "" || strings.EqualFold(q, "all") {
+		return nil, nil
+	}
+	return category.ActiveTaxonomy().ParseCategories(q)
+}
+
+func (f categoryFilter) apply(items []services.DataItem) []services.DataItem {
+	if len(f) == 0 {
+		return items
+	}
+	return filterItems(items, f.matches)
+}
+
+func (f categoryFilter) matches(item services.DataItem) bool {
+	if len(f) == 0 {
+		return true
+	}
+	for _, c := range itemCategories(item) {
+		if f[c] {
+			return true
+		}
+	}
+	return false
+}
+
+// itemCategories classifies an item with the active taxonomy, when it is
+// served, like itemStage.
+func itemCategories(item services.DataItem) []category.Category {
+	prop := item.Location.Properties
+	return category.Classify(prop.TypeName, prop.Other)
+}
+
+func categoryFilterError(err error) fiber.Map {
+	return fiber.Map{
+		"error":      err.Error(),
+		"categories": category.ActiveTaxonomy().Names(),
+	}
+}
+
+// countCategories counts items per category, in taxonomy order. An item with
+// several categories is counted under each.
+func countCategories(items []services.DataItem) []nameCount {
+	counts := make(map[category.Category]int)
+	for _, item := range items {
+		for _, c := range itemCategories(item) {
+			counts[c]++
+		}
+	}
+	result := make([]nameCount, 0, len(counts))
+	for _, name := range category.ActiveTaxonomy().Names() {
+		if n := counts[name]; n > 0 {
+			result = append(result, nameCount{Name: string(name), Count: n})
+		}
+	}
+	return result
+}
@@ -0,0 +1,82 @@
+package routes
+
+import (
+	"github.com/Nxdus/hatyai-api/dedupe"
+	"github.com/Nxdus/hatyai-api/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// dedupedItem is a list item, standing in for a group of repeat reports
+// when duplicates are collapsed.
+type dedupedItem struct {
+	services.DataItem
+	deduped
+}
+
+type deduped struct {
+	DuplicateIDs []string `json:"duplicate_ids,omitempty"`
+}
+
+func (d dedupedItem) MarshalJSON() ([]byte, error) {
+	return d.DataItem.MarshalJSONWith(classify(d.DataItem), d.deduped)
+}
+
+func duplicatesHandler(sosService services.SOSService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		data, err := sosService.GetSOS()
+		if err != nil {
+			return c.Status(502).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		groups := dedupe.Detect(data.Data.Data)
+		duplicates := 0
+		for _, g := range groups {
+			duplicates += len(g.IDs) - 1
+		}
+		return c.JSON(fiber.Map{
+			"count":      len(groups),
+			"duplicates": duplicates,
+			"groups":     groups,
+		})
+	}
+}
+
+// listItems returns items for a list response. With dedupe=true each group
+// of duplicates is collapsed into its first item, which lists the others in
+// duplicate_ids.
+func listItems(c *fiber.Ctx, items []services.DataItem) (interface{}, int) {
+	kept, linked := items, map[string][]string(nil)
+	if c.QueryBool("dedupe") {
+		kept, linked = dedupe.Collapse(items)
+	}
+	list := make([]dedupedItem, 0, len(kept))
+	for _, item := range kept {
+		list = append(list, dedupedItem{DataItem: item, deduped: deduped{linked[item.ID]}})
+	}
+	return list, len(list)
+}
+
+// collapseRanked removes duplicates from a ranking, keeping the highest
+// ranked request of each group.
+func collapseRanked(ranked []prioritizedDataItem) []prioritizedDataItem {
+	items := make([]services.DataItem, len(ranked))
+	for i, it := range ranked {
+		items[i] = it.DataItem
+	}
+	kept, linked := dedupe.Collapse(items)
+
+	keep := make(map[string]struct{}, len(kept))
+	for _, item := range kept {
+		keep[item.ID] = struct{}{}
+	}
+	collapsed := make([]prioritizedDataItem, 0, len(kept))
+	for _, it := range ranked {
+		if _, ok := keep[it.ID]; ok {
+			it.DuplicateIDs = linked[it.ID]
+			collapsed = append(collapsed, it)
+		}
+	}
+	return collapsed
+}
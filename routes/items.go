@@ -0,0 +1,149 @@
+package routes
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Nxdus/hatyai-api/history"
+	"github.com/Nxdus/hatyai-api/priority"
+	"github.com/Nxdus/hatyai-api/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// itemHandler looks up one request by _id, or by running number when
+// byRunning is set, and returns it with its priority. A request no longer
+// in the feed gets a 404 with the last time it was seen.
+func itemHandler(sosService services.SOSService, statusStore *history.Store, byRunning bool) fiber.Handler {
+	param := "id"
+	if byRunning {
+		param = "running_number"
+	}
+	return func(c *fiber.Ctx) error {
+		key := strings.TrimSpace(decodeParam(c.Params(param)))
+		if key == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": param + " is required"})
+		}
+
+		lang, ok := priority.ParseLang(c.Query("lang"))
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "lang must be th or en"})
+		}
+		ruleSet := priority.ActiveRuleSet()
+		rules, ok := ruleSet.Profile(strings.TrimSpace(c.Query("profile")))
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":    "unknown profile",
+				"profiles": ruleSet.ProfileNames(),
+			})
+		}
+
+		data, err := sosService.GetSOS()
+		if err != nil {
+			return c.Status(502).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		var item *services.DataItem
+		if byRunning {
+			item, ok = data.ItemByRunningNumber(key)
+		} else {
+			item, ok = data.ItemByID(key)
+		}
+		if !ok {
+			return itemNotFound(c, statusStore, key, byRunning)
+		}
+
+		opts := priority.Options{Lang: lang, Now: data.FetchedTime(), StatusSince: itemStatusSince(statusStore, item.ID)}
+		return c.JSON(prioritizedDataItem{
+			DataItem:    *item,
+			prioritized: prioritized{Priority: priority.Evaluate(rules, *item, opts)},
+		})
+	}
+}
+
+// itemStatusSince is statusSince for one item, read without loading the
+// whole status history.
+func itemStatusSince(store *history.Store, id string) map[string]time.Time {
+	since, ok, err := store.StatusSinceOf(id)
+	if err != nil {
+		log.Printf("status history unavailable for %s: %v", id, err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	return map[string]time.Time{id: since}
+}
+
+func itemNotFound(c *fiber.Ctx, statusStore *history.Store, key string, byRunning bool) error {
+	var seen *history.Seen
+	var found bool
+	var err error
+	if byRunning {
+		seen, found, err = statusStore.LastSeenRunning(key)
+	} else {
+		seen, found, err = statusStore.LastSeen(key)
+	}
+
+	resp := fiber.Map{"error": "item not found"}
+	switch {
+	case err != nil:
+		log.Printf("last seen lookup failed for %s: %v", key, err)
+	case found:
+		resp["error"] = "item is no longer in the feed"
+		resp["_id"] = seen.ID
+		resp["running_number"] = seen.RunningNumber
+		resp["last_seen"] = seen.LastSeen
+	}
+	return c.Status(fiber.StatusNotFound).JSON(resp)
+}
+
+// itemHistoryHandler returns the change timeline of one request, optionally
+// narrowed to the changes of one field.
+func itemHistoryHandler(sosService services.SOSService, statusStore *history.Store) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := strings.TrimSpace(decodeParam(c.Params("id")))
+		if id == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "id is required"})
+		}
+
+		data, err := sosService.GetSOS()
+		if err != nil {
+			return c.Status(502).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		_, inFeed := data.ItemByID(id)
+
+		events, err := statusStore.Events(id)
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
+		}
+		if len(events) == 0 && !inFeed {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "item not found"})
+		}
+
+		if field := strings.TrimSpace(c.Query("field")); field != "" {
+			filtered := make([]history.Event, 0, len(events))
+			for _, ev := range events {
+				for _, ch := range ev.Changes {
+					if ch.Field == field {
+						ev.Changes = []history.Change{ch}
+						filtered = append(filtered, ev)
+						break
+					}
+				}
+			}
+			events = filtered
+		}
+
+		return c.JSON(fiber.Map{
+			"_id":     id,
+			"in_feed": inFeed,
+			"count":   len(events),
+			"events":  events,
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package routes
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Nxdus/hatyai-api/category"
+	"github.com/Nxdus/hatyai-api/lifecycle"
+	"github.com/Nxdus/hatyai-api/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// lifecycleFilter keeps the items whose lifecycle is in the lifecycle query
+// parameter, a comma-separated list of stages or the groups open and
+// resolved. Without the parameter every item is kept.
+type lifecycleFilter map[lifecycle.Stage]bool
+
+func parseLifecycleFilter(c *fiber.Ctx) (lifecycleFilter, error) {
+	q := strings.TrimSpace(c.Query("lifecycle"))
+	if q == "" || strings.EqualFold(q, "all") {
+		return nil, nil
+	}
+	return lifecycle.ParseStages(q)
+}
+
+func (f lifecycleFilter) apply(items []services.DataItem) []services.DataItem {
+	if len(f) == 0 {
+		return items
+	}
+	return filterItems(items, f.matches)
+}
+
+func (f lifecycleFilter) matches(item services.DataItem) bool {
+	return len(f) == 0 || f[itemStage(item)]
+}
+
+// itemStage classifies an item's status_text with the active mapping. It
+// is done when the item is served rather than when the feed is parsed, so a
+// reloaded mapping applies to the cached snapshot at once.
+func itemStage(item services.DataItem) lifecycle.Stage {
+	return lifecycle.Classify(item.Location.Properties.StatusText)
+}
+
+// classified are the fields classified from an item's text that every
+// served item has under services.DerivedKey.
+type classified struct {
+	Lifecycle  lifecycle.Stage     `json:"lifecycle"`
+	Categories []category.Category `json:"categories"`
+}
+
+func classify(item services.DataItem) classified {
+	return classified{Lifecycle: itemStage(item), Categories: itemCategories(item)}
+}
+
+func lifecycleFilterError(err error) fiber.Map {
+	groups := make([]string, 0, len(lifecycle.Groups))
+	for name := range lifecycle.Groups {
+		groups = append(groups, name)
+	}
+	sort.Strings(groups)
+	return fiber.Map{
+		"error":  err.Error(),
+		"stages": lifecycle.Stages,
+		"groups": groups,
+	}
+}
@@ -0,0 +1,165 @@
+package routes
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/Nxdus/hatyai-api/history"
+	"github.com/Nxdus/hatyai-api/outcomes"
+	"github.com/Nxdus/hatyai-api/priority"
+	"github.com/Nxdus/hatyai-api/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// outcomeRequest records what a responder found for one item, identified by
+// _id or running_number. Profile selects the rules the prediction is
+// recorded under.
+type outcomeRequest struct {
+	ID            string `json:"_id"`
+	RunningNumber string `json:"running_number"`
+	Level         string `json:"level"`
+	Note          string `json:"note"`
+	RecordedBy    string `json:"recorded_by"`
+	Profile       string `json:"profile"`
+}
+
+func recordOutcomeHandler(sosService services.SOSService, store *outcomes.Store, statusStore *history.Store) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req outcomeRequest
+		if err := json.Unmarshal(c.Body(), &req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid JSON body: " + err.Error()})
+		}
+		req.ID = strings.TrimSpace(req.ID)
+		req.RunningNumber = strings.TrimSpace(req.RunningNumber)
+		if req.ID == "" && req.RunningNumber == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "_id or running_number is required"})
+		}
+
+		ruleSet := priority.ActiveRuleSet()
+		rules, ok := ruleSet.Profile(strings.TrimSpace(req.Profile))
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":    "unknown profile",
+				"profiles": ruleSet.ProfileNames(),
+			})
+		}
+		level := strings.ToLower(strings.TrimSpace(req.Level))
+		if !hasLevel(rules, level) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":  "unknown level",
+				"levels": levelNames(rules),
+			})
+		}
+
+		data, err := sosService.GetSOS()
+		if err != nil {
+			return c.Status(502).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		var found *services.DataItem
+		if req.ID != "" {
+			found, ok = data.ItemByID(req.ID)
+		} else {
+			found, ok = data.ItemByRunningNumber(req.RunningNumber)
+		}
+		if !ok {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "item not found in current data"})
+		}
+		// Outcomes are stored by _id, so blank ones would replace each other.
+		if strings.TrimSpace(found.ID) == "" {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "item has no _id; its outcome cannot be recorded"})
+		}
+
+		outcome := outcomes.Outcome{
+			ID:            found.ID,
+			RunningNumber: itemRunningNumber(*found),
+			Level:         level,
+			Note:          strings.TrimSpace(req.Note),
+			RecordedBy:    strings.TrimSpace(req.RecordedBy),
+			RecordedAt:    time.Now().UTC(),
+			RuleVersion:   rules.Version,
+			Profile:       rules.Profile,
+			Predicted:     priority.Evaluate(rules, *found, priority.Options{Now: data.FetchedTime(), StatusSince: statusSince(statusStore, data)}),
+		}
+		if err := store.Save(outcome); err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusCreated).JSON(outcome)
+	}
+}
+
+func listOutcomesHandler(store *outcomes.Store) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		list, err := store.All()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{
+			"count": len(list),
+			"items": list,
+		})
+	}
+}
+
+// calibrationHandler reports on outcomes recorded under one profile and one
+// rule_version, by default the active one, using that profile's levels.
+// rule_version=all (outcomes.AllVersions) mixes every version.
+func calibrationHandler(store *outcomes.Store) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ruleSet := priority.ActiveRuleSet()
+		rules, ok := ruleSet.Profile(strings.TrimSpace(c.Query("profile")))
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":    "unknown profile",
+				"profiles": ruleSet.ProfileNames(),
+			})
+		}
+		version := strings.TrimSpace(c.Query("rule_version"))
+		if version == "" {
+			version = rules.Version
+		}
+
+		list, err := store.All()
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
+		}
+		selected := outcomes.Select(list, rules.Profile, version)
+
+		return c.JSON(fiber.Map{
+			"profile":      rules.Profile,
+			"rule_version": version,
+			"report":       outcomes.Calibrate(selected, levelNames(rules)),
+		})
+	}
+}
+
+// levelNames lists a profile's levels from most to least severe.
+func levelNames(rules *priority.Rules) []string {
+	names := make([]string, 0, len(rules.Levels))
+	for _, lv := range rules.Levels {
+		names = append(names, lv.Name)
+	}
+	return names
+}
+
+// hasLevel reports whether name, lower-cased by the caller, is one of the
+// profile's levels. Level names are lower case, so outcomes compare with
+// predictions directly.
+func hasLevel(rules *priority.Rules, name string) bool {
+	for _, lv := range rules.Levels {
+		if lv.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func itemRunningNumber(item services.DataItem) string {
+	if item.RunningNumber != "" {
+		return item.RunningNumber
+	}
+	return item.Location.Properties.RunningNumber
+}
@@ -2,19 +2,29 @@ package routes
 
 import (
 	"context"
+	"log"
 	"net/url"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Nxdus/hatyai-api/areas"
+	"github.com/Nxdus/hatyai-api/history"
+	"github.com/Nxdus/hatyai-api/outcomes"
 	"github.com/Nxdus/hatyai-api/priority"
 	"github.com/Nxdus/hatyai-api/services"
+	"github.com/Nxdus/hatyai-api/stats"
+	"github.com/Nxdus/hatyai-api/trends"
 	"github.com/gofiber/fiber/v2"
 	"github.com/redis/go-redis/v9"
 )
 
-func RegisterRoutes(app *fiber.App, sosService services.SOSService, rdb *redis.Client) {
+// priorityRegion is the area rankings cover when no area is given. It was
+// the only area ranked before other regions were supported.
+const priorityRegion = "south"
+
+func RegisterRoutes(app *fiber.App, sosService services.SOSService, rdb *redis.Client, trendStore *trends.Store, statusStore *history.Store) {
 	app.Get("/v1", func(c *fiber.Ctx) error {
 		raw, err := sosService.GetRaw()
 		if err != nil {
@@ -36,10 +46,27 @@ func RegisterRoutes(app *fiber.App, sosService services.SOSService, rdb *redis.C
 		return c.JSON(fiber.Map{"status": "ok"})
 	})
 
-	app.Get("/v1/province/:name", func(c *fiber.Ctx) error {
-		name := decodeParam(c.Params("name"))
-		if strings.TrimSpace(name) == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "province is required"})
+	app.Get("/v1/province/:name", areaHandler(sosService, areas.LevelProvince))
+	app.Get("/v1/district/:name", areaHandler(sosService, areas.LevelDistrict))
+	app.Get("/v1/subdistrict/:name", areaHandler(sosService, areas.LevelSubdistrict))
+
+	app.Get("/v1/areas/search", func(c *fiber.Ctx) error {
+		q := strings.TrimSpace(c.Query("q"))
+		if q == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "q is required"})
+		}
+
+		levels := []areas.Level{areas.LevelProvince, areas.LevelDistrict, areas.LevelSubdistrict}
+		if lv := strings.ToLower(strings.TrimSpace(c.Query("level"))); lv != "" {
+			if _, ok := areaGetters[areas.Level(lv)]; !ok {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "level must be province, district or subdistrict"})
+			}
+			levels = []areas.Level{areas.Level(lv)}
+		}
+
+		limit := 10
+		if n, err := strconv.Atoi(strings.TrimSpace(c.Query("limit"))); err == nil && n > 0 {
+			limit = n
 		}
 
 		data, err := sosService.GetSOS()
@@ -49,21 +76,23 @@ func RegisterRoutes(app *fiber.App, sosService services.SOSService, rdb *redis.C
 			})
 		}
 
-		items := filterItems(data.Data.Data, name, func(item services.DataItem) string {
-			return item.Location.Properties.Province
-		})
+		candidates := make([]areas.Candidate, 0)
+		for _, level := range levels {
+			candidates = append(candidates, areaCandidates(data.Data.Data, level)...)
+		}
+		matches := areas.Default().Search(q, candidates, limit)
 
 		return c.JSON(fiber.Map{
-			"province": name,
-			"count":    len(items),
-			"items":    items,
+			"query": q,
+			"count": len(matches),
+			"items": matches,
 		})
 	})
 
-	app.Get("/v1/district/:name", func(c *fiber.Ctx) error {
-		name := decodeParam(c.Params("name"))
-		if strings.TrimSpace(name) == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "district is required"})
+	app.Get("/v1/area_summary", func(c *fiber.Ctx) error {
+		categories, err := parseCategoryFilter(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(categoryFilterError(err))
 		}
 
 		data, err := sosService.GetSOS()
@@ -73,23 +102,29 @@ func RegisterRoutes(app *fiber.App, sosService services.SOSService, rdb *redis.C
 			})
 		}
 
-		items := filterItems(data.Data.Data, name, func(item services.DataItem) string {
-			return item.Location.Properties.District
-		})
+		items := categories.apply(data.Data.Data)
+		if strings.EqualFold(strings.TrimSpace(c.Query("shape")), "tree") {
+			tree := buildAreaTree(items, data.FetchedTime())
+			return c.JSON(fiber.Map{
+				"total":     len(items),
+				"provinces": tree,
+			})
+		}
+
+		provinceCounts := buildCounts(items, func(item services.DataItem) string { return item.Location.Properties.Province })
+		districtCounts := buildCounts(items, func(item services.DataItem) string { return item.Location.Properties.District })
+		subdistrictCounts := buildCounts(items, func(item services.DataItem) string { return item.Location.Properties.SubDistrict })
+		categoryCounts := countCategories(items)
 
 		return c.JSON(fiber.Map{
-			"district": name,
-			"count":    len(items),
-			"items":    items,
+			"provinces":    fiber.Map{"total": len(provinceCounts), "items": provinceCounts},
+			"districts":    fiber.Map{"total": len(districtCounts), "items": districtCounts},
+			"subdistricts": fiber.Map{"total": len(subdistrictCounts), "items": subdistrictCounts},
+			"categories":   fiber.Map{"total": len(categoryCounts), "items": categoryCounts},
 		})
 	})
 
-	app.Get("/v1/subdistrict/:name", func(c *fiber.Ctx) error {
-		name := decodeParam(c.Params("name"))
-		if strings.TrimSpace(name) == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "subdistrict is required"})
-		}
-
+	app.Get("/v1/stats", func(c *fiber.Ctx) error {
 		data, err := sosService.GetSOS()
 		if err != nil {
 			return c.Status(502).JSON(fiber.Map{
@@ -97,34 +132,59 @@ func RegisterRoutes(app *fiber.App, sosService services.SOSService, rdb *redis.C
 			})
 		}
 
-		items := filterItems(data.Data.Data, name, func(item services.DataItem) string {
-			return item.Location.Properties.SubDistrict
-		})
-
-		return c.JSON(fiber.Map{
-			"subdistrict": name,
-			"count":       len(items),
-			"items":       items,
-		})
+		report, err := stats.Compute(data.Data.Data, splitList(c.Query("group_by")), splitList(c.Query("metric")), data.FetchedTime())
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(report)
 	})
 
-	app.Get("/v1/area_summary", func(c *fiber.Ctx) error {
-		data, err := sosService.GetSOS()
+	app.Get("/v1/trends", func(c *fiber.Ctx) error {
+		interval := time.Hour
+		if q := strings.TrimSpace(c.Query("interval")); q != "" {
+			d, err := trends.ParseInterval(q)
+			if err != nil || d < time.Minute {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "interval must be at least 1m (e.g. 15m, 1h, 1d)"})
+			}
+			interval = d
+		}
+
+		to := time.Now()
+		if q := strings.TrimSpace(c.Query("to")); q != "" {
+			t, ok := parseUpdatedAt(q)
+			if !ok {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "to must be RFC3339"})
+			}
+			to = t
+		}
+		from := to.Add(-24 * time.Hour)
+		if q := strings.TrimSpace(c.Query("from")); q != "" {
+			t, ok := parseUpdatedAt(q)
+			if !ok {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "from must be RFC3339"})
+			}
+			from = t
+		}
+
+		points, err := trendStore.Points(from, to)
 		if err != nil {
-			return c.Status(502).JSON(fiber.Map{
-				"error": err.Error(),
-			})
+			return c.Status(503).JSON(fiber.Map{"error": err.Error()})
 		}
 
-		items := data.Data.Data
-		provinceCounts := buildCounts(items, func(item services.DataItem) string { return item.Location.Properties.Province })
-		districtCounts := buildCounts(items, func(item services.DataItem) string { return item.Location.Properties.District })
-		subdistrictCounts := buildCounts(items, func(item services.DataItem) string { return item.Location.Properties.SubDistrict })
+		province := decodeParam(c.Query("province"))
+		metric := c.Query("metric", "total")
+		series, err := trends.Series(points, province, metric, interval)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
 
 		return c.JSON(fiber.Map{
-			"provinces":    fiber.Map{"total": len(provinceCounts), "items": provinceCounts},
-			"districts":    fiber.Map{"total": len(districtCounts), "items": districtCounts},
-			"subdistricts": fiber.Map{"total": len(subdistrictCounts), "items": subdistrictCounts},
+			"province": province,
+			"metric":   metric,
+			"interval": interval.String(),
+			"from":     from.UTC().Format(time.RFC3339),
+			"to":       to.UTC().Format(time.RFC3339),
+			"series":   series,
 		})
 	})
 
@@ -136,34 +196,68 @@ func RegisterRoutes(app *fiber.App, sosService services.SOSService, rdb *redis.C
 			})
 		}
 
-		items := make([]prioritizedDataItem, 0, len(filterItemsByLatLon(filterItemsByProvince(data.Data.Data, isSouthernProvince), InSouthernThailand)))
+		lang, ok := priority.ParseLang(c.Query("lang"))
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "lang must be th or en"})
+		}
 
-		for _, item := range filterItemsByLatLon(filterItemsByProvince(data.Data.Data, isSouthernProvince), InSouthernThailand) {
-			items = append(items, prioritizedDataItem{
-				DataItem: item,
-				Priority: priority.Calculate(item.Location.Properties),
+		ruleSet := priority.ActiveRuleSet()
+		rules, ok := ruleSet.Profile(strings.TrimSpace(c.Query("profile")))
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":    "unknown profile",
+				"profiles": ruleSet.ProfileNames(),
 			})
 		}
 
+		area, err := parseAreaFilter(c, priorityRegion)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(areaFilterError(err))
+		}
+		stages, err := parseLifecycleFilter(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(lifecycleFilterError(err))
+		}
+		categories, err := parseCategoryFilter(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(categoryFilterError(err))
+		}
+
+		// Scores are evaluated as of the snapshot's fetch time unless
+		// what_if_at moves the clock, in which case requests created after
+		// it are left out. It is a what-if, not a replay: every request keeps
+		// its current status_text and time in status.
+		asOf := data.FetchedTime()
+		items := categories.apply(stages.apply(area.apply(data.Data.Data)))
+		if q := strings.TrimSpace(c.Query("what_if_at")); q != "" {
+			t, ok := parseUpdatedAt(q)
+			if !ok {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "what_if_at must be RFC3339"})
+			}
+			asOf = t
+			items = filterItems(items, func(item services.DataItem) bool {
+				created, ok := parseUpdatedAt(item.CreatedAt)
+				return !ok || !created.After(asOf)
+			})
+		}
+
+		ranked := rankItems(items, rules, priority.Options{Lang: lang, Now: asOf, StatusSince: statusSince(statusStore, data)})
+		if c.QueryBool("dedupe") {
+			ranked = collapseRanked(ranked)
+		}
+
 		levelFilter := strings.ToLower(strings.TrimSpace(c.Query("priority_level")))
 		if levelFilter != "" && levelFilter != "all" {
-			filtered := make([]prioritizedDataItem, 0, len(items))
-			for _, it := range items {
+			filtered := make([]prioritizedDataItem, 0, len(ranked))
+			for _, it := range ranked {
 				if strings.ToLower(it.Priority.Level) == levelFilter {
 					filtered = append(filtered, it)
 				}
 			}
-			items = filtered
+			ranked = filtered
 		}
 
-		sort.SliceStable(items, func(i, j int) bool {
-			if items[i].Priority.Score == items[j].Priority.Score {
-				return mostRecentUpdate(items[i]).After(mostRecentUpdate(items[j]))
-			}
-			return items[i].Priority.Score > items[j].Priority.Score
-		})
-
-		limit := len(items)
+		limit := len(ranked)
 		if q := strings.TrimSpace(c.Query("limit")); q != "" {
 			if n, err := strconv.Atoi(q); err == nil && n > 0 && n < limit {
 				limit = n
@@ -171,12 +265,39 @@ func RegisterRoutes(app *fiber.App, sosService services.SOSService, rdb *redis.C
 		}
 
 		return c.JSON(fiber.Map{
-			"count": len(items),
-			"items": items[:limit],
+			"rule_version": rules.Version,
+			"profile":      rules.Profile,
+			"as_of":        asOf.UTC().Format(time.RFC3339),
+			"area":         area,
+			"count":        len(ranked),
+			"items":        ranked[:limit],
 		})
 	})
 
+	app.Post("/v1/priority/simulate", simulateHandler(sosService, statusStore))
+
+	outcomeStore := outcomes.NewStore(rdb)
+	app.Post("/v1/priority/outcomes", recordOutcomeHandler(sosService, outcomeStore, statusStore))
+	app.Get("/v1/priority/outcomes", listOutcomesHandler(outcomeStore))
+	app.Get("/v1/priority/calibration", calibrationHandler(outcomeStore))
+
+	app.Get("/v1/duplicates", duplicatesHandler(sosService))
+	app.Get("/v1/search", searchHandler(sosService, statusStore))
+
+	app.Get("/v1/items/:id", itemHandler(sosService, statusStore, false))
+	app.Get("/v1/items/:id/history", itemHistoryHandler(sosService, statusStore))
+	app.Get("/v1/running/:running_number", itemHandler(sosService, statusStore, true))
+
 	app.Get("/v1/south", func(c *fiber.Ctx) error {
+		stages, err := parseLifecycleFilter(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(lifecycleFilterError(err))
+		}
+		categories, err := parseCategoryFilter(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(categoryFilterError(err))
+		}
+
 		data, err := sosService.GetSOS()
 		if err != nil {
 			return c.Status(502).JSON(fiber.Map{
@@ -184,15 +305,20 @@ func RegisterRoutes(app *fiber.App, sosService services.SOSService, rdb *redis.C
 			})
 		}
 
-		items := filterItemsByLatLon(filterItemsByProvince(data.Data.Data, isSouthernProvince), InSouthernThailand)
+		items, count := listItems(c, categories.apply(stages.apply(filterItemsByLatLon(filterItemsByProvince(data.Data.Data, isSouthernProvince), InSouthernThailand))))
 
 		return c.JSON(fiber.Map{
-			"count": len(items),
+			"count": count,
 			"items": items,
 		})
 	})
 
 	app.Get("/v1/area_summary/south", func(c *fiber.Ctx) error {
+		categories, err := parseCategoryFilter(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(categoryFilterError(err))
+		}
+
 		data, err := sosService.GetSOS()
 		if err != nil {
 			return c.Status(502).JSON(fiber.Map{
@@ -200,16 +326,27 @@ func RegisterRoutes(app *fiber.App, sosService services.SOSService, rdb *redis.C
 			})
 		}
 
-		items := filterItemsByProvince(data.Data.Data, isSouthernProvince)
+		items := categories.apply(filterItemsByProvince(data.Data.Data, isSouthernProvince))
+		if strings.EqualFold(strings.TrimSpace(c.Query("shape")), "tree") {
+			tree := buildAreaTree(items, data.FetchedTime())
+			return c.JSON(fiber.Map{
+				"region":    "south",
+				"total":     len(items),
+				"provinces": tree,
+			})
+		}
+
 		provinceCounts := buildCounts(items, func(item services.DataItem) string { return item.Location.Properties.Province })
 		districtCounts := buildCounts(items, func(item services.DataItem) string { return item.Location.Properties.District })
 		subdistrictCounts := buildCounts(items, func(item services.DataItem) string { return item.Location.Properties.SubDistrict })
+		categoryCounts := countCategories(items)
 
 		return c.JSON(fiber.Map{
 			"region":       "south",
 			"provinces":    fiber.Map{"total": len(provinceCounts), "items": provinceCounts},
 			"districts":    fiber.Map{"total": len(districtCounts), "items": districtCounts},
 			"subdistricts": fiber.Map{"total": len(subdistrictCounts), "items": subdistrictCounts},
+			"categories":   fiber.Map{"total": len(categoryCounts), "items": categoryCounts},
 		})
 	})
 }
@@ -219,37 +356,6 @@ type nameCount struct {
 	Count int    `json:"count"`
 }
 
-var southernProvinces = map[string]struct{}{
-	"phuket":                               {},
-	"krabi":                                {},
-	"phang nga":                            {},
-	"ranong":                               {},
-	"chumphon":                             {},
-	"surat thani":                          {},
-	"nakhon si thammarat":                  {},
-	"phatthalung":                          {},
-	"trang":                                {},
-	"satun":                                {},
-	"songkhla":                             {},
-	"pattani":                              {},
-	"yala":                                 {},
-	"narathiwat":                           {},
-	"\u0e20\u0e39\u0e40\u0e01\u0e47\u0e15": {}, // Thai: \u0e20\u0e39\u0e40\u0e01\u0e47\u0e15 (Phuket)
-	"\u0e01\u0e23\u0e30\u0e1a\u0e35\u0e48": {}, // Thai: \u0e01\u0e23\u0e30\u0e1a\u0e35\u0e48 (Krabi)
-	"\u0e1e\u0e31\u0e07\u0e07\u0e32":       {}, // Thai: \u0e1e\u0e31\u0e07\u0e07\u0e32 (Phang Nga)
-	"\u0e23\u0e30\u0e19\u0e2d\u0e07":       {}, // Thai: \u0e23\u0e30\u0e19\u0e2d\u0e07 (Ranong)
-	"\u0e0a\u0e38\u0e21\u0e1e\u0e23":       {}, // Thai: \u0e0a\u0e38\u0e21\u0e1e\u0e23 (Chumphon)
-	"\u0e2a\u0e38\u0e23\u0e32\u0e29\u0e11\u0e23\u0e4c\u0e18\u0e32\u0e19\u0e35":       {}, // Thai: \u0e2a\u0e38\u0e23\u0e32\u0e29\u0e11\u0e23\u0e4c\u0e18\u0e32\u0e19\u0e35 (Surat Thani)
-	"\u0e19\u0e04\u0e23\u0e28\u0e23\u0e35\u0e18\u0e23\u0e23\u0e21\u0e23\u0e32\u0e0a": {}, // Thai: \u0e19\u0e04\u0e23\u0e28\u0e23\u0e35\u0e18\u0e23\u0e23\u0e21\u0e23\u0e32\u0e0a (Nakhon Si Thammarat)
-	"\u0e1e\u0e31\u0e17\u0e25\u0e38\u0e07":                                           {}, // Thai: \u0e1e\u0e31\u0e17\u0e25\u0e38\u0e07 (Phatthalung)
-	"\u0e15\u0e23\u0e31\u0e07":                                                       {}, // Thai: \u0e15\u0e23\u0e31\u0e07 (Trang)
-	"\u0e2a\u0e15\u0e39\u0e25":                                                       {}, // Thai: \u0e2a\u0e15\u0e39\u0e25 (Satun)
-	"\u0e2a\u0e07\u0e02\u0e25\u0e32":                                                 {}, // Thai: \u0e2a\u0e07\u0e02\u0e25\u0e32 (Songkhla)
-	"\u0e1b\u0e31\u0e15\u0e15\u0e32\u0e19\u0e35":                                     {}, // Thai: \u0e1b\u0e31\u0e15\u0e15\u0e32\u0e19\u0e35 (Pattani)
-	"\u0e22\u0e30\u0e25\u0e32":                                                       {}, // Thai: \u0e22\u0e30\u0e25\u0e32 (Yala)
-	"\u0e19\u0e23\u0e32\u0e18\u0e34\u0e27\u0e32\u0e2a":                               {}, // Thai: \u0e19\u0e23\u0e32\u0e18\u0e34\u0e27\u0e32\u0e2a (Narathiwat)
-}
-
 var SouthernPolygon = [][2]float64{
 	{98.20, 8.30},  // Phuket NW
 	{98.30, 7.70},  // Phuket South
@@ -300,15 +406,78 @@ func InSouthernThailand(lat, lon float64) bool {
 	return PointInPolygon(lat, lon, SouthernPolygon)
 }
 
-func filterItems(items []services.DataItem, target string, get func(services.DataItem) string) []services.DataItem {
-	target = strings.ToLower(strings.TrimSpace(target))
-	if target == "" {
-		return nil
+var areaGetters = map[areas.Level]func(services.DataItem) string{
+	areas.LevelProvince:    func(item services.DataItem) string { return item.Location.Properties.Province },
+	areas.LevelDistrict:    func(item services.DataItem) string { return item.Location.Properties.District },
+	areas.LevelSubdistrict: func(item services.DataItem) string { return item.Location.Properties.SubDistrict },
+}
+
+func areaHandler(sosService services.SOSService, level areas.Level) fiber.Handler {
+	label := string(level)
+	get := areaGetters[level]
+	return func(c *fiber.Ctx) error {
+		name := strings.TrimSpace(decodeParam(c.Params("name")))
+		if name == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": label + " is required"})
+		}
+		stages, err := parseLifecycleFilter(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(lifecycleFilterError(err))
+		}
+		categories, err := parseCategoryFilter(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(categoryFilterError(err))
+		}
+		gz := areas.Default()
+		area, resolved := gz.Resolve(level, name)
+		if err := ambiguousArea(gz, resolved, nil, level, name); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(areaFilterError(err))
+		}
+
+		data, err := sosService.GetSOS()
+		if err != nil {
+			return c.Status(502).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		resp := fiber.Map{label: name}
+		var items []services.DataItem
+		if resolved {
+			items = filterItems(data.Data.Data, func(item services.DataItem) bool {
+				return gz.Matches(area, get(item))
+			})
+			resp[label] = area.NameTH
+			resp["name_en"] = area.NameEN
+			resp["code"] = area.Code
+		} else {
+			key := areas.Key(name)
+			items = filterItems(data.Data.Data, func(item services.DataItem) bool {
+				return key != "" && areas.Key(get(item)) == key
+			})
+		}
+
+		resp["items"], resp["count"] = listItems(c, categories.apply(stages.apply(items)))
+		if len(items) == 0 {
+			resp["did_you_mean"] = gz.Suggest(name, areaCandidates(data.Data.Data, level), 5)
+		}
+		return c.JSON(resp)
 	}
+}
 
+func areaCandidates(items []services.DataItem, level areas.Level) []areas.Candidate {
+	counts := buildCounts(items, areaGetters[level])
+	candidates := make([]areas.Candidate, 0, len(counts))
+	for _, nc := range counts {
+		candidates = append(candidates, areas.Candidate{Name: nc.Name, Level: level, Count: nc.Count})
+	}
+	return candidates
+}
+
+func filterItems(items []services.DataItem, match func(services.DataItem) bool) []services.DataItem {
 	filtered := make([]services.DataItem, 0)
 	for _, item := range items {
-		if strings.ToLower(get(item)) == target {
+		if match(item) {
 			filtered = append(filtered, item)
 		}
 	}
@@ -364,9 +533,22 @@ func buildCounts(items []services.DataItem, get func(services.DataItem) string)
 	return result
 }
 
+// isSouthernProvince reports whether province resolves to a province of
+// the gazetteer's southern region.
 func isSouthernProvince(province string) bool {
-	_, ok := southernProvinces[strings.ToLower(strings.TrimSpace(province))]
-	return ok
+	south, _ := areas.RegionByID("south")
+	area, ok := areas.Default().Resolve(areas.LevelProvince, province)
+	return ok && south.Contains(area.Code)
+}
+
+func splitList(val string) []string {
+	parts := make([]string, 0)
+	for _, p := range strings.Split(val, ",") {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
 }
 
 func decodeParam(val string) string {
@@ -381,7 +563,50 @@ func decodeParam(val string) string {
 
 type prioritizedDataItem struct {
 	services.DataItem
+	prioritized
+}
+
+// prioritized are the fields prioritizedDataItem serves with the item's
+// other derived fields.
+type prioritized struct {
 	Priority priority.Result `json:"priority"`
+	deduped
+}
+
+func (p prioritizedDataItem) MarshalJSON() ([]byte, error) {
+	return p.DataItem.MarshalJSONWith(classify(p.DataItem), p.prioritized)
+}
+
+// statusSince loads when the items of data entered their current status.
+// Without it waiting time is counted from created_at, so a Redis error only
+// costs precision.
+func statusSince(store *history.Store, data *services.APIResponse) map[string]time.Time {
+	since, err := store.StatusSince(data)
+	if err != nil {
+		log.Printf("status history unavailable: %v", err)
+		return nil
+	}
+	return since
+}
+
+// rankItems scores items with rules and orders them the way /v1/priority
+// lists them: highest score first, most recently updated first on ties.
+func rankItems(items []services.DataItem, rules *priority.Rules, opts priority.Options) []prioritizedDataItem {
+	ranked := make([]prioritizedDataItem, 0, len(items))
+	for _, item := range items {
+		ranked = append(ranked, prioritizedDataItem{
+			DataItem:    item,
+			prioritized: prioritized{Priority: priority.Evaluate(rules, item, opts)},
+		})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Priority.Score == ranked[j].Priority.Score {
+			return mostRecentUpdate(ranked[i]).After(mostRecentUpdate(ranked[j]))
+		}
+		return ranked[i].Priority.Score > ranked[j].Priority.Score
+	})
+	return ranked
 }
 
 func mostRecentUpdate(item prioritizedDataItem) time.Time {
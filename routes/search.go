@@ -0,0 +1,111 @@
+package routes
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Nxdus/hatyai-api/history"
+	"github.com/Nxdus/hatyai-api/priority"
+	"github.com/Nxdus/hatyai-api/search"
+	"github.com/Nxdus/hatyai-api/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type searchResult struct {
+	prioritizedDataItem
+	matched
+}
+
+type matched struct {
+	Score    float64          `json:"score"`
+	Snippets []search.Snippet `json:"snippets"`
+}
+
+func (r searchResult) MarshalJSON() ([]byte, error) {
+	return r.DataItem.MarshalJSONWith(classify(r.DataItem), struct {
+		prioritized
+		matched
+	}{r.prioritized, r.matched})
+}
+
+// searchHandler finds requests whose other or disease text matches q, best
+// match first. Unlike /v1/priority it covers the whole country unless an
+// area is given.
+func searchHandler(sosService services.SOSService, statusStore *history.Store) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		q := strings.TrimSpace(c.Query("q"))
+		if q == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "q is required"})
+		}
+		query, err := search.ParseQuery(q)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		lang, ok := priority.ParseLang(c.Query("lang"))
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "lang must be th or en"})
+		}
+		ruleSet := priority.ActiveRuleSet()
+		rules, ok := ruleSet.Profile(strings.TrimSpace(c.Query("profile")))
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":    "unknown profile",
+				"profiles": ruleSet.ProfileNames(),
+			})
+		}
+
+		area, err := parseAreaFilter(c, "")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(areaFilterError(err))
+		}
+		stages, err := parseLifecycleFilter(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(lifecycleFilterError(err))
+		}
+		categories, err := parseCategoryFilter(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(categoryFilterError(err))
+		}
+		levelFilter := strings.ToLower(strings.TrimSpace(c.Query("priority_level")))
+
+		data, err := sosService.GetSOS()
+		if err != nil {
+			return c.Status(502).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		opts := priority.Options{Lang: lang, Now: data.FetchedTime(), StatusSince: statusSince(statusStore, data)}
+		results := make([]searchResult, 0)
+		for _, hit := range search.For(data).Search(query) {
+			if !area.matches(hit.Item) || !stages.matches(hit.Item) || !categories.matches(hit.Item) {
+				continue
+			}
+			result := priority.Evaluate(rules, hit.Item, opts)
+			if levelFilter != "" && levelFilter != "all" && strings.ToLower(result.Level) != levelFilter {
+				continue
+			}
+			results = append(results, searchResult{
+				prioritizedDataItem: prioritizedDataItem{DataItem: hit.Item, prioritized: prioritized{Priority: result}},
+				matched:             matched{Score: hit.Score, Snippets: hit.Snippets},
+			})
+		}
+
+		limit := len(results)
+		if q := strings.TrimSpace(c.Query("limit")); q != "" {
+			if n, err := strconv.Atoi(q); err == nil && n > 0 && n < limit {
+				limit = n
+			}
+		}
+
+		return c.JSON(fiber.Map{
+			"query":        q,
+			"rule_version": rules.Version,
+			"profile":      rules.Profile,
+			"area":         area,
+			"count":        len(results),
+			"items":        results[:limit],
+		})
+	}
+}
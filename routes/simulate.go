@@ -0,0 +1,184 @@
+package routes
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/Nxdus/hatyai-api/history"
+	"github.com/Nxdus/hatyai-api/priority"
+	"github.com/Nxdus/hatyai-api/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// simulateRequest carries either a complete candidate rule file in Rules,
+// or Overrides to lay over one profile of the active rules.
+type simulateRequest struct {
+	Rules     json.RawMessage `json:"rules"`
+	Profile   string          `json:"profile"`
+	Overrides json.RawMessage `json:"overrides"`
+	Limit     int             `json:"limit"`
+}
+
+type rulesSummary struct {
+	RuleVersion string         `json:"rule_version"`
+	Profile     string         `json:"profile"`
+	Levels      map[string]int `json:"levels"`
+}
+
+type rankChange struct {
+	ID            string `json:"_id"`
+	RunningNumber string `json:"running_number"`
+	FromScore     int    `json:"from_score"`
+	ToScore       int    `json:"to_score"`
+	FromLevel     string `json:"from_level"`
+	ToLevel       string `json:"to_level"`
+	FromRank      int    `json:"from_rank"`
+	ToRank        int    `json:"to_rank"`
+	RankDelta     int    `json:"rank_delta"`
+}
+
+func simulateHandler(sosService services.SOSService, statusStore *history.Store) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req simulateRequest
+		if err := json.Unmarshal(c.Body(), &req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid JSON body: " + err.Error()})
+		}
+
+		ruleSet := priority.ActiveRuleSet()
+		profile := strings.TrimSpace(req.Profile)
+		active, ok := ruleSet.Profile(profile)
+		if !ok && len(req.Rules) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":    "unknown profile",
+				"profiles": ruleSet.ProfileNames(),
+			})
+		}
+
+		var candidate *priority.Rules
+		switch {
+		case len(req.Rules) > 0:
+			rs, err := priority.ParseRuleSet(req.Rules)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			}
+			if candidate, ok = rs.Profile(profile); !ok {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error":    "profile not defined in candidate rules",
+					"profiles": rs.ProfileNames(),
+				})
+			}
+			if active, ok = ruleSet.Profile(candidate.Profile); !ok {
+				active, _ = ruleSet.Profile("")
+			}
+		case len(req.Overrides) > 0:
+			var err error
+			candidate, err = active.Override(req.Overrides, active.Version+"+overrides")
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			}
+		default:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "rules or overrides is required"})
+		}
+
+		area, err := parseAreaFilter(c, priorityRegion)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(areaFilterError(err))
+		}
+
+		data, err := sosService.GetSOS()
+		if err != nil {
+			return c.Status(502).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		items := area.apply(data.Data.Data)
+		opts := priority.Options{Now: data.FetchedTime(), StatusSince: statusSince(statusStore, data)}
+		before := rankItems(items, active, opts)
+		after := rankItems(items, candidate, opts)
+
+		limit := 100
+		if req.Limit > 0 {
+			limit = req.Limit
+		}
+		levelChanges, rankChanges := compareRankings(before, after)
+		moved := len(rankChanges)
+		if len(levelChanges) > limit {
+			levelChanges = levelChanges[:limit]
+		}
+		if len(rankChanges) > limit {
+			rankChanges = rankChanges[:limit]
+		}
+
+		return c.JSON(fiber.Map{
+			"area":          area,
+			"count":         len(items),
+			"active":        summarizeRanking(active, before),
+			"candidate":     summarizeRanking(candidate, after),
+			"level_changes": levelChanges,
+			"rank_changes":  rankChanges,
+			"moved":         moved,
+		})
+	}
+}
+
+func summarizeRanking(rules *priority.Rules, ranked []prioritizedDataItem) rulesSummary {
+	levels := make(map[string]int)
+	for _, lv := range rules.Levels {
+		levels[lv.Name] = 0
+	}
+	for _, it := range ranked {
+		levels[it.Priority.Level]++
+	}
+	return rulesSummary{RuleVersion: rules.Version, Profile: rules.Profile, Levels: levels}
+}
+
+// compareRankings pairs items by _id across both rankings. It returns the
+// items whose level changed, and every item whose rank moved, largest move
+// first.
+func compareRankings(before, after []prioritizedDataItem) ([]rankChange, []rankChange) {
+	afterRank := make(map[string]int, len(after))
+	for i, it := range after {
+		afterRank[it.ID] = i
+	}
+
+	levelChanges := make([]rankChange, 0)
+	rankChanges := make([]rankChange, 0)
+	for i, it := range before {
+		j, ok := afterRank[it.ID]
+		if !ok {
+			continue
+		}
+		next := after[j]
+		change := rankChange{
+			ID:            it.ID,
+			RunningNumber: it.RunningNumber,
+			FromScore:     it.Priority.Score,
+			ToScore:       next.Priority.Score,
+			FromLevel:     it.Priority.Level,
+			ToLevel:       next.Priority.Level,
+			FromRank:      i + 1,
+			ToRank:        j + 1,
+			RankDelta:     i - j,
+		}
+		if change.FromLevel != change.ToLevel {
+			levelChanges = append(levelChanges, change)
+		}
+		if change.RankDelta != 0 {
+			rankChanges = append(rankChanges, change)
+		}
+	}
+
+	sort.SliceStable(rankChanges, func(i, j int) bool {
+		return abs(rankChanges[i].RankDelta) > abs(rankChanges[j].RankDelta)
+	})
+	return levelChanges, rankChanges
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,109 @@
+package search
+
+import (
+	"sync"
+
+	"github.com/Nxdus/hatyai-api/services"
+	"github.com/Nxdus/hatyai-api/textnorm"
+)
+
+// Fields that are indexed, in the order snippets are returned.
+var fields = []struct {
+	name string
+	get  func(services.LocationProperty) string
+}{
+	{"other", func(p services.LocationProperty) string { return p.Other }},
+	{"disease", func(p services.LocationProperty) string { return p.Disease }},
+}
+
+type posting struct {
+	doc, field, pos int
+}
+
+// document is one field of one item: its normalised text and words.
+type document struct {
+	text   string
+	tokens []textnorm.Token
+	// original is the field as upstream wrote it, and sources maps each
+	// byte of text back to it, so snippets show what was written.
+	original string
+	sources  []textnorm.Source
+	// compact is text without the spaces next to Thai characters, and
+	// offsets maps each byte in compact back to text, for substring matches.
+	compact string
+	offsets []int
+}
+
+// Index is an inverted index over the text fields of one snapshot. Words
+// come from textnorm.Segment, so Thai text without spaces is searchable by
+// word.
+type Index struct {
+	items    []services.DataItem
+	docs     [][]document // by item, then field
+	postings map[string][]posting
+}
+
+func NewIndex(items []services.DataItem) *Index {
+	idx := &Index{
+		items:    items,
+		docs:     make([][]document, len(items)),
+		postings: make(map[string][]posting),
+	}
+	for i, item := range items {
+		idx.docs[i] = make([]document, len(fields))
+		for f, field := range fields {
+			original := field.get(item.Location.Properties)
+			text, sources := textnorm.NormalizeSource(original)
+			if text == "" {
+				continue
+			}
+			doc := document{text: text, tokens: textnorm.Segment(text), original: original, sources: sources}
+			doc.compact, doc.offsets = compactWithOffsets(text)
+			idx.docs[i][f] = doc
+			for pos, tok := range doc.tokens {
+				idx.postings[tok.Text] = append(idx.postings[tok.Text], posting{doc: i, field: f, pos: pos})
+			}
+		}
+	}
+	return idx
+}
+
+var cache struct {
+	mu   sync.Mutex
+	data *services.APIResponse
+	idx  *Index
+}
+
+// For returns the index of a snapshot, building it on the first search after
+// the snapshot changes.
+func For(data *services.APIResponse) *Index {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.data != data {
+		cache.data = data
+		cache.idx = NewIndex(data.Data.Data)
+	}
+	return cache.idx
+}
+
+// compactWithOffsets removes the spaces textnorm.Compact removes, keeping
+// for every byte of the result its offset in text.
+func compactWithOffsets(text string) (string, []int) {
+	runes := []rune(text)
+	out := make([]byte, 0, len(text))
+	offsets := make([]int, 0, len(text))
+	off := 0
+	for i, r := range runes {
+		size := len(string(r))
+		if r == ' ' && i > 0 && i < len(runes)-1 && (textnorm.IsThai(runes[i-1]) || textnorm.IsThai(runes[i+1])) {
+			off += size
+			continue
+		}
+		for b := 0; b < size; b++ {
+			offsets = append(offsets, off+b)
+		}
+		out = append(out, string(r)...)
+		off += size
+	}
+	return string(out), offsets
+}
@@ -0,0 +1,271 @@
+package search
+
+import (
+	"errors"
+	"html"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/Nxdus/hatyai-api/services"
+	"github.com/Nxdus/hatyai-api/textnorm"
+)
+
+const (
+	snippetContext = 40 // runes either side of the first match
+	markOpen       = "<mark>"
+	markClose      = "</mark>"
+)
+
+// term is one query term or quoted phrase, as the words it segments into.
+// A term of several words matches only where they appear in order.
+type term struct {
+	words   []string
+	compact string
+}
+
+// Query is a parsed search query; an item must match all of its terms.
+type Query struct {
+	terms []term
+}
+
+type Snippet struct {
+	Field string `json:"field"`
+	Text  string `json:"text"`
+}
+
+// Hit is one matching item. Snippets show each matching field as written
+// around its first match, HTML-escaped, with every match wrapped in
+// <mark></mark>.
+type Hit struct {
+	Item     services.DataItem `json:"-"`
+	Score    float64           `json:"score"`
+	Snippets []Snippet         `json:"snippets"`
+}
+
+type span struct{ start, end int }
+
+// ParseQuery splits q into terms: "double quoted" phrases and otherwise
+// space-separated words.
+func ParseQuery(q string) (Query, error) {
+	var raw []string
+	for i, part := range strings.Split(q, `"`) {
+		if i%2 == 1 {
+			raw = append(raw, part)
+			continue
+		}
+		raw = append(raw, strings.Fields(part)...)
+	}
+
+	terms := make([]term, 0, len(raw))
+	for _, r := range raw {
+		tokens := textnorm.Segment(r)
+		if len(tokens) == 0 {
+			continue
+		}
+		t := term{compact: textnorm.Compact(r)}
+		for _, tok := range tokens {
+			t.words = append(t.words, tok.Text)
+		}
+		terms = append(terms, t)
+	}
+	if len(terms) == 0 {
+		return Query{}, errors.New("q must contain at least one word")
+	}
+	return Query{terms: terms}, nil
+}
+
+// Search returns the items matching every term, best match first. A term is
+// matched word by word through the index, or, when that finds nothing in
+// the whole snapshot, as a substring of the text, so words the segmenter
+// cuts differently are still found.
+func (idx *Index) Search(q Query) []Hit {
+	matches := make(map[int]map[int][]span) // item → field → spans
+	var score map[int]float64
+
+	for n, t := range q.terms {
+		found := idx.matchWords(t)
+		if len(found) == 0 {
+			found = idx.matchSubstring(t)
+		}
+		idf := math.Log(1 + float64(len(idx.items))/float64(len(found)+1))
+
+		next := make(map[int]float64, len(found))
+		for item, byField := range found {
+			if n > 0 {
+				if _, ok := score[item]; !ok {
+					continue
+				}
+			}
+			count := 0
+			if matches[item] == nil {
+				matches[item] = make(map[int][]span)
+			}
+			for f, spans := range byField {
+				matches[item][f] = append(matches[item][f], spans...)
+				count += len(spans)
+			}
+			next[item] = score[item] + float64(count)*idf
+		}
+		score = next
+		if len(score) == 0 {
+			return []Hit{}
+		}
+	}
+
+	hits := make([]Hit, 0, len(score))
+	order := make([]int, 0, len(score))
+	for item := range score {
+		order = append(order, item)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		if score[order[i]] != score[order[j]] {
+			return score[order[i]] > score[order[j]]
+		}
+		return order[i] < order[j]
+	})
+	for _, item := range order {
+		hit := Hit{Item: idx.items[item], Score: math.Round(score[item]*100) / 100}
+		for f := range fields {
+			if spans := matches[item][f]; len(spans) > 0 {
+				hit.Snippets = append(hit.Snippets, Snippet{Field: fields[f].name, Text: idx.docs[item][f].snippet(spans)})
+			}
+		}
+		hits = append(hits, hit)
+	}
+	return hits
+}
+
+// matchWords finds the term's words in order through the postings of its
+// first word.
+func (idx *Index) matchWords(t term) map[int]map[int][]span {
+	found := make(map[int]map[int][]span)
+	for _, p := range idx.postings[t.words[0]] {
+		tokens := idx.docs[p.doc][p.field].tokens
+		if p.pos+len(t.words) > len(tokens) {
+			continue
+		}
+		ok := true
+		for k := 1; k < len(t.words); k++ {
+			if tokens[p.pos+k].Text != t.words[k] {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		if found[p.doc] == nil {
+			found[p.doc] = make(map[int][]span)
+		}
+		found[p.doc][p.field] = append(found[p.doc][p.field], span{tokens[p.pos].Start, tokens[p.pos+len(t.words)-1].End})
+	}
+	return found
+}
+
+func (idx *Index) matchSubstring(t term) map[int]map[int][]span {
+	found := make(map[int]map[int][]span)
+	if t.compact == "" {
+		return found
+	}
+	for i, docs := range idx.docs {
+		for f, doc := range docs {
+			for from := 0; ; {
+				at := strings.Index(doc.compact[from:], t.compact)
+				if at < 0 {
+					break
+				}
+				start := from + at
+				end := start + len(t.compact)
+				if found[i] == nil {
+					found[i] = make(map[int][]span)
+				}
+				found[i][f] = append(found[i][f], span{doc.offsets[start], doc.offsets[end-1] + 1})
+				from = end
+			}
+		}
+	}
+	return found
+}
+
+// originalSpan maps a span of the normalised text to the original text. Thai
+// marks may have been reordered, so every byte of the span is looked at.
+func (d *document) originalSpan(sp span) span {
+	out := span{d.sources[sp.start].Start, d.sources[sp.start].End}
+	for _, src := range d.sources[sp.start:sp.end] {
+		out.start = min(out.start, src.Start)
+		out.end = max(out.end, src.End)
+	}
+	return out
+}
+
+// snippet cuts the original text around the first match, escapes it for
+// HTML and marks every match inside the cut.
+func (d *document) snippet(spans []span) string {
+	for i, sp := range spans {
+		spans[i] = d.originalSpan(sp)
+	}
+	text := d.original
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	merged := spans[:1]
+	for _, sp := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if sp.start <= last.end {
+			last.end = max(last.end, sp.end)
+			continue
+		}
+		merged = append(merged, sp)
+	}
+	spans = merged
+
+	from := runeOffset(text, spans[0].start, -snippetContext)
+	to := runeOffset(text, spans[0].end, snippetContext)
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, sp := range spans {
+		if sp.start < pos || sp.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:sp.start]))
+		b.WriteString(markOpen)
+		b.WriteString(html.EscapeString(text[sp.start:sp.end]))
+		b.WriteString(markClose)
+		pos = sp.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// runeOffset moves n runes from byte offset off, staying inside text.
+func runeOffset(text string, off, n int) int {
+	runes := 0
+	if n < 0 {
+		for off > 0 && runes > n {
+			off--
+			for off > 0 && !isRuneStart(text[off]) {
+				off--
+			}
+			runes--
+		}
+		return off
+	}
+	for off < len(text) && runes < n {
+		off++
+		for off < len(text) && !isRuneStart(text[off]) {
+			off++
+		}
+		runes++
+	}
+	return off
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,35 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/Nxdus/hatyai-api/services"
+)
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		other, q, want string
+	}{
+		{"ต้องการ ยา ด่วน", "ยา", "ต้องการ <mark>ยา</mark> ด่วน"},
+		{"Need INSULIN now", "insulin", "Need <mark>INSULIN</mark> now"},
+		{"<script>alert(1)</script> insulin", "insulin", "&lt;script&gt;alert(1)&lt;/script&gt; <mark>insulin</mark>"},
+		{"ยา<b>เบาหวาน</b>", `"เบาหวาน"`, "ยา&lt;b&gt;<mark>เบาหวาน</mark>&lt;/b&gt;"},
+		{"ต้องการน้ำ  ๒  ลัง", "2", "ต้องการน้ำ  <mark>๒</mark>  ลัง"},
+	}
+	for _, tt := range tests {
+		var item services.DataItem
+		item.Location.Properties.Other = tt.other
+		q, err := ParseQuery(tt.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hits := NewIndex([]services.DataItem{item}).Search(q)
+		if len(hits) != 1 || len(hits[0].Snippets) != 1 {
+			t.Errorf("Search(%q) in %q = %+v, want one snippet", tt.q, tt.other, hits)
+			continue
+		}
+		if got := hits[0].Snippets[0].Text; got != tt.want {
+			t.Errorf("snippet for %q in %q = %q, want %q", tt.q, tt.other, got, tt.want)
+		}
+	}
+}
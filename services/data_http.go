@@ -3,6 +3,7 @@ package services
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"log"
 	"net/http"
 	"time"
@@ -51,10 +52,19 @@ func (h *httpFetcher) Fetch(etag string) (*APIResponse, string, bool, error) {
 		return nil, "", false, errors.New("Upstream API error, Status: " + resp.Status)
 	}
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", false, err
+	}
 	var result APIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := json.Unmarshal(body, &result); err != nil {
 		return nil, "", false, err
 	}
+	result.raw = body
+	result.received = time.Now()
+	if _, ok := result.upstreamFetchedTime(); !ok {
+		log.Printf("upstream fetched_at %q is missing or malformed, using fetch time %s", result.FetchedAt, result.received.Format(time.RFC3339))
+	}
 
 	newETag := resp.Header.Get("ETag")
 	log.Printf("Fetched %d items from upstream (%s, status=%s, etag=%s)", len(result.Data.Data), apiURL, resp.Status, newETag)
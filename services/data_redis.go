@@ -20,24 +20,34 @@ const (
 type SOSService interface {
 	GetRaw() ([]byte, error)
 	GetSOS() (*APIResponse, error)
+	// OnRefresh registers fn to be called with every new snapshot, whether
+	// this instance fetched it from upstream or another instance left it in
+	// Redis. fn may see the same snapshot more than once.
+	OnRefresh(fn func(*APIResponse))
 }
 
 type redisSOSService struct {
-	redis    *redis.Client
-	fetcher  APIFetcher
-	refreshM sync.Mutex
-	memCache atomic.Value
+	redis     *redis.Client
+	fetcher   APIFetcher
+	refreshM  sync.Mutex
+	memCache  atomic.Value
+	listenerM sync.RWMutex
+	listeners []func(*APIResponse)
+	notified  atomic.Value // etag of the last snapshot passed to listeners
+	drift     schemaDrift
 }
 
 type cachedPayload struct {
-	ETag string          `json:"etag"`
-	JSON json.RawMessage `json:"json"`
+	ETag     string          `json:"etag"`
+	JSON     json.RawMessage `json:"json"`
+	Received time.Time       `json:"received_at"`
 }
 
 type memoryCache struct {
 	raw      []byte
 	parsed   *APIResponse
 	etag     string
+	received time.Time
 	expires  time.Time
 }
 
@@ -61,7 +71,11 @@ func (s *redisSOSService) GetRaw() ([]byte, error) {
 	if err == nil {
 		var cached cachedPayload
 		if json.Unmarshal(val, &cached) == nil {
-			s.storeMemoryCache(cached.JSON, cached.ETag, nil)
+			if cached.Received.IsZero() {
+				cached.Received = time.Now()
+			}
+			s.storeMemoryCache(cached.JSON, cached.ETag, nil, cached.Received)
+			s.notifyShared(cached)
 			s.tryRefresh(cached.ETag)
 			return cached.JSON, nil
 		}
@@ -75,12 +89,14 @@ func (s *redisSOSService) GetRaw() ([]byte, error) {
 		return nil, errors.New("no data returned from fetcher")
 	}
 
-	raw, err := json.Marshal(data)
+	raw, err := data.rawJSON()
 	if err != nil {
 		return nil, err
 	}
 
-	s.saveRawCache(etag, raw, data)
+	s.saveRawCache(etag, raw, data, data.received)
+	s.drift.check(data)
+	s.notifyRefresh(data, etag)
 	return raw, nil
 }
 
@@ -98,6 +114,9 @@ func (s *redisSOSService) GetSOS() (*APIResponse, error) {
 	if err := json.Unmarshal(raw, &data); err != nil {
 		return nil, err
 	}
+	if cached := s.loadMemoryCache(); cached != nil {
+		data.received = cached.received
+	}
 	s.updateParsedCache(&data)
 	return &data, nil
 }
@@ -120,18 +139,59 @@ func (s *redisSOSService) tryRefresh(etag string) {
 			return
 		}
 
-		raw, err := json.Marshal(data)
+		raw, err := data.rawJSON()
 		if err != nil {
 			return
 		}
-		s.saveRawCache(newETag, raw, data)
+		s.saveRawCache(newETag, raw, data, data.received)
+		s.drift.check(data)
+		s.notifyRefresh(data, newETag)
+	}()
+}
+
+func (s *redisSOSService) OnRefresh(fn func(*APIResponse)) {
+	s.listenerM.Lock()
+	defer s.listenerM.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// notifyShared passes a snapshot found in Redis to the listeners unless it
+// is the one they last saw, so an instance records the snapshots other
+// instances fetched as well as its own and the history has no gaps.
+func (s *redisSOSService) notifyShared(cached cachedPayload) {
+	if last, _ := s.notified.Load().(string); cached.ETag != "" && cached.ETag == last {
+		return
+	}
+	var data APIResponse
+	if err := json.Unmarshal(cached.JSON, &data); err != nil {
+		return
+	}
+	data.raw = cached.JSON
+	data.received = cached.Received
+	s.notifyRefresh(&data, cached.ETag)
+}
+
+func (s *redisSOSService) notifyRefresh(data *APIResponse, etag string) {
+	s.notified.Store(etag)
+	s.listenerM.RLock()
+	listeners := append([]func(*APIResponse){}, s.listeners...)
+	s.listenerM.RUnlock()
+
+	if len(listeners) == 0 {
+		return
+	}
+	go func() {
+		for _, fn := range listeners {
+			fn(data)
+		}
 	}()
 }
 
-func (s *redisSOSService) saveRawCache(etag string, raw []byte, parsed *APIResponse) {
+func (s *redisSOSService) saveRawCache(etag string, raw []byte, parsed *APIResponse, received time.Time) {
 	payload := cachedPayload{
-		ETag: etag,
-		JSON: raw,
+		ETag:     etag,
+		JSON:     raw,
+		Received: received,
 	}
 
 	bytes, err := json.Marshal(payload)
@@ -149,20 +209,21 @@ func (s *redisSOSService) saveRawCache(etag string, raw []byte, parsed *APIRespo
 		log.Printf("redis cache updated (etag=%s, ttl=%s)", etag, redisTTL)
 	}
 
-	s.storeMemoryCache(raw, etag, parsed)
+	s.storeMemoryCache(raw, etag, parsed, received)
 }
 
-func (s *redisSOSService) storeMemoryCache(raw []byte, etag string, parsed *APIResponse) {
+func (s *redisSOSService) storeMemoryCache(raw []byte, etag string, parsed *APIResponse, received time.Time) {
 	ttl := redisTTL - 5*time.Second
 	if ttl < 5*time.Second {
 		ttl = redisTTL
 	}
 
 	s.memCache.Store(&memoryCache{
-		raw:     raw,
-		parsed:  parsed,
-		etag:    etag,
-		expires: time.Now().Add(ttl),
+		raw:      raw,
+		parsed:   parsed,
+		etag:     etag,
+		received: received,
+		expires:  time.Now().Add(ttl),
 	})
 }
 
@@ -183,10 +244,11 @@ func (s *redisSOSService) updateParsedCache(parsed *APIResponse) {
 		return
 	}
 	s.memCache.Store(&memoryCache{
-		raw:     current.raw,
-		parsed:  parsed,
-		etag:    current.etag,
-		expires: current.expires,
+		raw:      current.raw,
+		parsed:   parsed,
+		etag:     current.etag,
+		received: current.received,
+		expires:  current.expires,
 	})
 }
 
@@ -195,5 +257,5 @@ func (s *redisSOSService) touchCache(etag string) {
 	if current == nil || len(current.raw) == 0 {
 		return
 	}
-	s.saveRawCache(etag, current.raw, current.parsed)
+	s.saveRawCache(etag, current.raw, current.parsed, current.received)
 }
@@ -1,12 +1,54 @@
 package services
 
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
 type APIResponse struct {
 	FetchedAt string     `json:"fetched_at"`
 	Data      NestedData `json:"data"`
+
+	Extra Extras `json:"-"`
+
+	index    itemIndex
+	raw      []byte
+	received time.Time // when this service fetched the snapshot
+}
+
+// rawJSON returns the snapshot as upstream sent it, or re-encoded if it was
+// not fetched with its body.
+func (r *APIResponse) rawJSON() ([]byte, error) {
+	if r.raw != nil {
+		return r.raw, nil
+	}
+	return json.Marshal(r)
+}
+
+// FetchedTime is when the snapshot was fetched from upstream. If fetched_at
+// is missing or malformed it is when this service fetched it, which stays
+// the same for as long as the snapshot is cached; only a snapshot that did
+// not come through a service falls back to the current time.
+func (r *APIResponse) FetchedTime() time.Time {
+	if t, ok := r.upstreamFetchedTime(); ok {
+		return t
+	}
+	if !r.received.IsZero() {
+		return r.received
+	}
+	return time.Now()
+}
+
+func (r *APIResponse) upstreamFetchedTime() (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(r.FetchedAt))
+	return t, err == nil
 }
 
 type NestedData struct {
 	Data []DataItem `json:"data"`
+
+	Extra Extras `json:"-"`
 }
 
 type DataItem struct {
@@ -15,31 +57,52 @@ type DataItem struct {
 	RunningNumber string   `json:"running_number"`
 	UpdatedAt     string   `json:"updated_at"`
 	CreatedAt     string   `json:"created_at"`
+
+	Extra Extras `json:"-"`
+}
+
+func (d *DataItem) UnmarshalJSON(data []byte) error {
+	type plain DataItem
+	if err := json.Unmarshal(data, (*plain)(d)); err != nil {
+		return err
+	}
+	extras, err := unknownFields(data, dataItemFields)
+	if err != nil {
+		return err
+	}
+	d.Extra = extras
+	return nil
 }
 
 type Location struct {
 	Type       string           `json:"type"`
 	Properties LocationProperty `json:"properties"`
 	Geometry   Geometry         `json:"geometry"`
+
+	Extra Extras `json:"-"`
 }
 
 type LocationProperty struct {
-	Other            string        `json:"other"`
-	Victims          []interface{} `json:"victims"`
-	Patient          int           `json:"patient"`
-	Province         string        `json:"province"`
-	District         string        `json:"district"`
-	SubDistrict      string        `json:"subdistrict"`
-	SickLevelSummary int           `json:"sick_level_summary"`
-	RunningNumber    string        `json:"running_number"`
-	StatusText       string        `json:"status_text"`
-	TypeName         string        `json:"type_name"`
-	Ages             string        `json:"ages"`
-	Disease          string        `json:"disease"`
-	UpdatedAt        string        `json:"updated_at"`
+	Other            string   `json:"other"`
+	Victims          []Victim `json:"victims"`
+	Patient          int      `json:"patient"`
+	Province         string   `json:"province"`
+	District         string   `json:"district"`
+	SubDistrict      string   `json:"subdistrict"`
+	SickLevelSummary int      `json:"sick_level_summary"`
+	RunningNumber    string   `json:"running_number"`
+	StatusText       string   `json:"status_text"`
+	TypeName         string   `json:"type_name"`
+	Ages             string   `json:"ages"`
+	Disease          string   `json:"disease"`
+	UpdatedAt        string   `json:"updated_at"`
+
+	Extra Extras `json:"-"`
 }
 
 type Geometry struct {
 	Type        string    `json:"type"`
 	Coordinates []float64 `json:"coordinates"`
+
+	Extra Extras `json:"-"`
 }
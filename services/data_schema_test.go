@@ -0,0 +1,26 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFetchedTime(t *testing.T) {
+	received := time.Date(2025, 11, 28, 12, 0, 30, 0, time.UTC)
+	tests := []struct {
+		fetchedAt string
+		received  time.Time
+		want      time.Time
+	}{
+		{"2025-11-28T12:00:00Z", received, time.Date(2025, 11, 28, 12, 0, 0, 0, time.UTC)},
+		{" 2025-11-28T19:00:00.5+07:00 ", time.Time{}, time.Date(2025, 11, 28, 12, 0, 0, 5e8, time.UTC)},
+		{"", received, received},
+		{"yesterday", received, received},
+	}
+	for _, tt := range tests {
+		r := APIResponse{FetchedAt: tt.fetchedAt, received: tt.received}
+		if got := r.FetchedTime(); !got.Equal(tt.want) {
+			t.Errorf("FetchedTime(%q, received %v) = %v, want %v", tt.fetchedAt, tt.received, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"log"
+	"sort"
+	"sync"
+)
+
+// schemaDrift logs upstream fields the schema does not declare, once per
+// field for the life of the process. They are still passed on through
+// Extras; the log is the cue to give them a typed field.
+type schemaDrift struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+type driftField struct {
+	example string
+	count   int
+}
+
+func (s *schemaDrift) check(data *APIResponse) {
+	found := make(map[string]*driftField)
+	note := func(prefix string, extras Extras, id string) {
+		for name := range extras {
+			path := prefix + name
+			f, ok := found[path]
+			if !ok {
+				f = &driftField{example: id}
+				found[path] = f
+			}
+			f.count++
+		}
+	}
+	// Fields outside the items are named from the top of the response,
+	// item fields from the top of the item.
+	note("response.", data.Extra, "")
+	note("response.data.", data.Data.Extra, "")
+	for _, item := range data.Data.Data {
+		note("", item.Extra, item.ID)
+		note("location.", item.Location.Extra, item.ID)
+		note("location.properties.", item.Location.Properties.Extra, item.ID)
+		note("location.geometry.", item.Location.Geometry.Extra, item.ID)
+	}
+	if len(found) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen == nil {
+		s.seen = make(map[string]bool)
+	}
+	paths := make([]string, 0, len(found))
+	for path := range found {
+		if !s.seen[path] {
+			s.seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		f := found[path]
+		if f.example == "" {
+			log.Printf("upstream schema drift: new field %q", path)
+			continue
+		}
+		log.Printf("upstream schema drift: new field %q in %d items (e.g. _id=%s)", path, f.count, f.example)
+	}
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Extras holds the fields of an upstream object that the typed schema does
+// not declare, so fields upstream adds without notice are passed on instead
+// of being dropped.
+type Extras map[string]json.RawMessage
+
+var (
+	responseFields         = jsonFields(reflect.TypeOf(APIResponse{}))
+	nestedDataFields       = jsonFields(reflect.TypeOf(NestedData{}))
+	dataItemFields         = jsonFields(reflect.TypeOf(DataItem{}))
+	locationFields         = jsonFields(reflect.TypeOf(Location{}))
+	locationPropertyFields = jsonFields(reflect.TypeOf(LocationProperty{}))
+	geometryFields         = jsonFields(reflect.TypeOf(Geometry{}))
+)
+
+// jsonFields returns the JSON names of the fields of struct type t.
+func jsonFields(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		names[name] = true
+	}
+	return names
+}
+
+// unknownFields returns the members of a JSON object that are not in known.
+func unknownFields(data []byte, known map[string]bool) (Extras, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	var extras Extras
+	for name, val := range fields {
+		if known[name] {
+			continue
+		}
+		if extras == nil {
+			extras = make(Extras)
+		}
+		extras[name] = val
+	}
+	return extras, nil
+}
+
+// appendFields adds the members of extras to the JSON object obj, in name
+// order.
+func appendFields(obj []byte, extras map[string]json.RawMessage) ([]byte, error) {
+	if len(extras) == 0 {
+		return obj, nil
+	}
+	names := make([]string, 0, len(extras))
+	for name := range extras {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.Write(bytes.TrimSuffix(bytes.TrimSpace(obj), []byte("}")))
+	first := bytes.Equal(bytes.TrimSpace(obj), []byte("{}"))
+	for _, name := range names {
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(extras[name])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// DerivedKey is the member under which MarshalJSONWith serves the fields
+// this API works out for an item, so they never share a name with an
+// upstream field.
+const DerivedKey = "_derived"
+
+// MarshalJSONWith encodes the item with the fields of each of derived,
+// structs, under DerivedKey. Types that embed DataItem use it in their own
+// MarshalJSON, since DataItem's encodes the upstream fields alone. If upstream ever sends a DerivedKey field of its own, that is kept
+// and the derived fields are left out.
+func (d DataItem) MarshalJSONWith(derived ...interface{}) ([]byte, error) {
+	item, err := d.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := d.Extra[DerivedKey]; ok {
+		return item, nil
+	}
+	fields := []byte("{}")
+	for _, v := range derived {
+		if v == nil {
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		fields = joinObjects(fields, b)
+	}
+	return appendFields(item, map[string]json.RawMessage{DerivedKey: fields})
+}
+
+// joinObjects puts the members of JSON objects a and b in one object.
+func joinObjects(a, b []byte) []byte {
+	a, b = bytes.TrimSpace(a), bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("{}")) {
+		return a
+	}
+	out := append([]byte(nil), a[:len(a)-1]...)
+	if !bytes.Equal(a, []byte("{}")) {
+		out = append(out, ',')
+	}
+	return append(out, b[1:]...)
+}
+
+func (d DataItem) MarshalJSON() ([]byte, error) {
+	type plain DataItem
+	b, err := json.Marshal(plain(d))
+	if err != nil {
+		return nil, err
+	}
+	return appendFields(b, d.Extra)
+}
+
+func (l *Location) UnmarshalJSON(data []byte) error {
+	type plain Location
+	if err := json.Unmarshal(data, (*plain)(l)); err != nil {
+		return err
+	}
+	extras, err := unknownFields(data, locationFields)
+	if err != nil {
+		return err
+	}
+	l.Extra = extras
+	return nil
+}
+
+func (l Location) MarshalJSON() ([]byte, error) {
+	type plain Location
+	b, err := json.Marshal(plain(l))
+	if err != nil {
+		return nil, err
+	}
+	return appendFields(b, l.Extra)
+}
+
+func (p *LocationProperty) UnmarshalJSON(data []byte) error {
+	type plain LocationProperty
+	if err := json.Unmarshal(data, (*plain)(p)); err != nil {
+		return err
+	}
+	extras, err := unknownFields(data, locationPropertyFields)
+	if err != nil {
+		return err
+	}
+	p.Extra = extras
+	return nil
+}
+
+func (p LocationProperty) MarshalJSON() ([]byte, error) {
+	type plain LocationProperty
+	b, err := json.Marshal(plain(p))
+	if err != nil {
+		return nil, err
+	}
+	return appendFields(b, p.Extra)
+}
+
+func (r *APIResponse) UnmarshalJSON(data []byte) error {
+	type plain APIResponse
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+	extras, err := unknownFields(data, responseFields)
+	if err != nil {
+		return err
+	}
+	r.Extra = extras
+	return nil
+}
+
+func (r *APIResponse) MarshalJSON() ([]byte, error) {
+	type plain APIResponse
+	b, err := json.Marshal((*plain)(r))
+	if err != nil {
+		return nil, err
+	}
+	return appendFields(b, r.Extra)
+}
+
+func (n *NestedData) UnmarshalJSON(data []byte) error {
+	type plain NestedData
+	if err := json.Unmarshal(data, (*plain)(n)); err != nil {
+		return err
+	}
+	extras, err := unknownFields(data, nestedDataFields)
+	if err != nil {
+		return err
+	}
+	n.Extra = extras
+	return nil
+}
+
+func (n NestedData) MarshalJSON() ([]byte, error) {
+	type plain NestedData
+	b, err := json.Marshal(plain(n))
+	if err != nil {
+		return nil, err
+	}
+	return appendFields(b, n.Extra)
+}
+
+func (g *Geometry) UnmarshalJSON(data []byte) error {
+	type plain Geometry
+	if err := json.Unmarshal(data, (*plain)(g)); err != nil {
+		return err
+	}
+	extras, err := unknownFields(data, geometryFields)
+	if err != nil {
+		return err
+	}
+	g.Extra = extras
+	return nil
+}
+
+func (g Geometry) MarshalJSON() ([]byte, error) {
+	type plain Geometry
+	b, err := json.Marshal(plain(g))
+	if err != nil {
+		return nil, err
+	}
+	return appendFields(b, g.Extra)
+}
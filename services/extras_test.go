@@ -0,0 +1,139 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMarshalJSONWith(t *testing.T) {
+	var item DataItem
+	err := json.Unmarshal([]byte(`{
+		"_id": "a1",
+		"lifecycle": "upstream stage",
+		"priority": 5,
+		"location": {"properties": {"status_text": "ช่วยเหลือแล้ว", "extra_prop": true}}
+	}`), &item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := item.MarshalJSONWith(struct {
+		Lifecycle string `json:"lifecycle"`
+	}{"rescued"}, nil, struct {
+		Priority string `json:"priority"`
+	}{"computed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+
+	want := map[string]interface{}{
+		"lifecycle": "upstream stage",
+		"priority":  float64(5),
+		"_derived": map[string]interface{}{
+			"lifecycle": "rescued",
+			"priority":  "computed",
+		},
+	}
+	for k, v := range want {
+		if !reflect.DeepEqual(got[k], v) {
+			t.Errorf("%s = %#v, want %#v", k, got[k], v)
+		}
+	}
+	props := got["location"].(map[string]interface{})["properties"].(map[string]interface{})
+	if props["extra_prop"] != true {
+		t.Errorf("location.properties.extra_prop = %v, want true", props["extra_prop"])
+	}
+}
+
+func TestMarshalJSONWithUpstreamDerivedKey(t *testing.T) {
+	var item DataItem
+	if err := json.Unmarshal([]byte(`{"_id": "a1", "_derived": "upstream"}`), &item); err != nil {
+		t.Fatal(err)
+	}
+	b, err := item.MarshalJSONWith(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+	if string(got[DerivedKey]) != `"upstream"` {
+		t.Errorf("%s = %s, want the upstream value", DerivedKey, got[DerivedKey])
+	}
+}
+
+func TestMarshalJSONRoundTrip(t *testing.T) {
+	in := `{"_id":"a1","location":{"type":"","properties":{"status_text":"x"},"geometry":{"type":"","coordinates":null}},"running_number":"","updated_at":"","created_at":""}`
+	var item DataItem
+	if err := json.Unmarshal([]byte(in), &item); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again DataItem
+	if err := json.Unmarshal(b, &again); err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Extra) != 0 {
+		t.Errorf("re-decoded item has extras %v; derived fields leaked into the upstream encoding", again.Extra)
+	}
+}
+
+func TestUnknownFieldsRoundTrip(t *testing.T) {
+	in := `{
+		"new_top": 1,
+		"data": {
+			"new_data": 2,
+			"data": [{
+				"_id": "a1",
+				"new_item": 3,
+				"location": {
+					"new_location": 4,
+					"properties": {"status_text": "x", "new_properties": 5},
+					"geometry": {"type": "Point", "coordinates": [100, 13], "new_geometry": 6}
+				}
+			}]
+		}
+	}`
+	var resp APIResponse
+	if err := json.Unmarshal([]byte(in), &resp); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+
+	data := got["data"].(map[string]interface{})
+	item := data["data"].([]interface{})[0].(map[string]interface{})
+	location := item["location"].(map[string]interface{})
+	levels := []struct {
+		name   string
+		obj    map[string]interface{}
+		field  string
+		expect float64
+	}{
+		{"response", got, "new_top", 1},
+		{"data", data, "new_data", 2},
+		{"item", item, "new_item", 3},
+		{"location", location, "new_location", 4},
+		{"properties", location["properties"].(map[string]interface{}), "new_properties", 5},
+		{"geometry", location["geometry"].(map[string]interface{}), "new_geometry", 6},
+	}
+	for _, l := range levels {
+		if l.obj[l.field] != l.expect {
+			t.Errorf("%s.%s = %v, want %v", l.name, l.field, l.obj[l.field], l.expect)
+		}
+	}
+}
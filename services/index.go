@@ -0,0 +1,56 @@
+package services
+
+import (
+	"strings"
+	"sync"
+)
+
+// itemIndex maps ids and running numbers to positions in a snapshot. It is
+// built on first lookup; snapshots are not modified after they are parsed.
+type itemIndex struct {
+	once      sync.Once
+	byID      map[string]int
+	byRunning map[string]int
+}
+
+// ItemByID returns the item with the given _id.
+func (r *APIResponse) ItemByID(id string) (*DataItem, bool) {
+	idx := r.itemIndex()
+	i, ok := idx.byID[strings.TrimSpace(id)]
+	if !ok {
+		return nil, false
+	}
+	return &r.Data.Data[i], true
+}
+
+// ItemByRunningNumber returns the item with the given running number, taken
+// from the item itself or, failing that, from its properties.
+func (r *APIResponse) ItemByRunningNumber(running string) (*DataItem, bool) {
+	idx := r.itemIndex()
+	i, ok := idx.byRunning[strings.TrimSpace(running)]
+	if !ok {
+		return nil, false
+	}
+	return &r.Data.Data[i], true
+}
+
+func (r *APIResponse) itemIndex() *itemIndex {
+	r.index.once.Do(func() {
+		items := r.Data.Data
+		r.index.byID = make(map[string]int, len(items))
+		r.index.byRunning = make(map[string]int, len(items))
+		for i, item := range items {
+			if item.ID != "" {
+				r.index.byID[item.ID] = i
+			}
+			running := item.RunningNumber
+			if running == "" {
+				running = item.Location.Properties.RunningNumber
+			}
+			if running != "" {
+				r.index.byRunning[running] = i
+			}
+		}
+	})
+	return &r.index
+}
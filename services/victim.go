@@ -0,0 +1,117 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Victim is one person listed in a request. Upstream victims are entered
+// by different forms and arrive as objects with varying key names, as bare
+// descriptions or as bare ages, so decoding never fails: whatever cannot be
+// read is left empty and the original JSON is kept in Raw.
+type Victim struct {
+	Age          string   `json:"age,omitempty"`
+	Sex          string   `json:"sex,omitempty"`
+	Condition    string   `json:"condition,omitempty"`
+	MedicalNeeds []string `json:"medical_needs,omitempty"`
+
+	Raw json.RawMessage `json:"-"`
+}
+
+// Key names seen for each victim field, compared in lower case.
+var victimKeys = map[string][]string{
+	"age":           {"age", "ages", "age_text", "อายุ"},
+	"sex":           {"sex", "gender", "เพศ"},
+	"condition":     {"condition", "conditions", "symptom", "symptoms", "disease", "illness", "note", "อาการ", "โรค"},
+	"medical_needs": {"medical_needs", "medical_need", "needs", "need", "medicine", "medicines", "ยา", "ความต้องการ"},
+}
+
+func (v *Victim) UnmarshalJSON(data []byte) error {
+	*v = Victim{Raw: append(json.RawMessage(nil), data...)}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil
+	}
+	switch trimmed[0] {
+	case '{':
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(trimmed, &fields) != nil {
+			return nil
+		}
+		lower := make(map[string]json.RawMessage, len(fields))
+		for k, val := range fields {
+			lower[strings.ToLower(strings.TrimSpace(k))] = val
+		}
+		v.Age = strings.Join(victimField(lower, "age"), ", ")
+		v.Sex = strings.Join(victimField(lower, "sex"), ", ")
+		v.Condition = strings.Join(victimField(lower, "condition"), ", ")
+		v.MedicalNeeds = victimField(lower, "medical_needs")
+	case '"':
+		var text string
+		if json.Unmarshal(trimmed, &text) == nil {
+			v.Condition = strings.TrimSpace(text)
+		}
+	case 'n':
+		return nil
+	default:
+		var n float64
+		if json.Unmarshal(trimmed, &n) == nil {
+			v.Age = strconv.FormatFloat(n, 'f', -1, 64)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON writes the victim back out as upstream sent it, so clients
+// see the same shape the source API uses.
+func (v Victim) MarshalJSON() ([]byte, error) {
+	if len(v.Raw) > 0 {
+		return v.Raw, nil
+	}
+	type plain Victim
+	return json.Marshal(plain(v))
+}
+
+// victimField collects the values under every alias of field as strings.
+func victimField(fields map[string]json.RawMessage, field string) []string {
+	var values []string
+	for _, key := range victimKeys[field] {
+		if raw, ok := fields[key]; ok {
+			values = append(values, jsonStrings(raw)...)
+		}
+	}
+	return values
+}
+
+// jsonStrings reads a string, number, bool or array of those as text.
+func jsonStrings(raw json.RawMessage) []string {
+	var list []json.RawMessage
+	if json.Unmarshal(raw, &list) == nil {
+		var values []string
+		for _, el := range list {
+			values = append(values, jsonStrings(el)...)
+		}
+		return values
+	}
+
+	var val interface{}
+	if json.Unmarshal(raw, &val) != nil {
+		return nil
+	}
+	var s string
+	switch t := val.(type) {
+	case string:
+		s = strings.TrimSpace(t)
+	case float64:
+		s = strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		s = strconv.FormatBool(t)
+	}
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
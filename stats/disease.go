@@ -0,0 +1,45 @@
+package stats
+
+import (
+	"strings"
+
+	"github.com/Nxdus/hatyai-api/services"
+	"github.com/Nxdus/hatyai-api/textnorm"
+)
+
+type diseaseCategory struct {
+	name     string
+	keywords []string
+}
+
+var diseaseCategories = []diseaseCategory{
+	{name: "cardiovascular", keywords: []string{"หัวใจ", "ความดัน", "เส้นเลือด", "หลอดเลือด"}},
+	{name: "kidney", keywords: []string{"ไต", "ฟอกไต"}},
+	{name: "diabetes", keywords: []string{"เบาหวาน", "น้ำตาล"}},
+	{name: "cancer", keywords: []string{"มะเร็ง", "เคมีบำบัด"}},
+	{name: "respiratory", keywords: []string{"หอบ", "หืด", "ปอด", "ออกซิเจน", "เครื่องช่วยหายใจ"}},
+	{name: "mobility", keywords: []string{"ติดเตียง", "พิการ", "อัมพาต", "อัมพฤกษ์", "เดินไม่ได้"}},
+	{name: "pregnancy", keywords: []string{"ตั้งครรภ์", "ครรภ์", "ใกล้คลอด", "คลอด"}},
+	{name: "mental", keywords: []string{"จิตเวช", "ซึมเศร้า", "สมองเสื่อม"}},
+}
+
+func diseaseDimension(item services.DataItem) []string {
+	text := textnorm.Compact(item.Location.Properties.Disease)
+	if text == "" || text == "-" || text == "ไม่มี" {
+		return []string{"none"}
+	}
+
+	matched := make([]string, 0, 1)
+	for _, cat := range diseaseCategories {
+		for _, kw := range cat.keywords {
+			if strings.Contains(text, kw) {
+				matched = append(matched, cat.name)
+				break
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return []string{"other"}
+	}
+	return matched
+}
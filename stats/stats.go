@@ -0,0 +1,310 @@
+package stats
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Nxdus/hatyai-api/areas"
+	"github.com/Nxdus/hatyai-api/category"
+	"github.com/Nxdus/hatyai-api/lifecycle"
+	"github.com/Nxdus/hatyai-api/priority"
+	"github.com/Nxdus/hatyai-api/services"
+)
+
+const unknownValue = "unknown"
+
+// dimension extracts the value(s) an item contributes to a group or
+// breakdown. Disease and category can yield several values for one item.
+type dimension func(services.DataItem) []string
+
+var dimensions = map[string]dimension{
+	"province":    areaDimension(1),
+	"district":    areaDimension(2),
+	"subdistrict": areaDimension(3),
+	"type_name":   textDimension(func(p services.LocationProperty) string { return p.TypeName }),
+	"status_text": textDimension(func(p services.LocationProperty) string { return p.StatusText }),
+	"lifecycle":   lifecycleDimension,
+	"sick_level":  sickLevelDimension,
+	"age_band":    ageBandDimension,
+	"disease":     diseaseDimension,
+	"category":    categoryDimension,
+}
+
+// Sum metrics add a number per item; every other metric name is a
+// breakdown over the dimension of the same name.
+var sumMetrics = map[string]func(services.DataItem) int{
+	"items":    func(services.DataItem) int { return 1 },
+	"patients": func(item services.DataItem) int { return item.Location.Properties.Patient },
+	"victims":  func(item services.DataItem) int { return len(item.Location.Properties.Victims) },
+}
+
+var DefaultMetrics = []string{"items", "patients", "victims"}
+
+type Row map[string]interface{}
+
+type Report struct {
+	GroupBy []string `json:"group_by"`
+	Metrics []string `json:"metrics"`
+	Total   Row      `json:"total"`
+	Groups  []Row    `json:"groups"`
+}
+
+// dimensionsAt returns every dimension, with priority_level scored as of
+// at.
+func dimensionsAt(at time.Time) map[string]dimension {
+	dims := make(map[string]dimension, len(dimensions)+1)
+	for name, d := range dimensions {
+		dims[name] = d
+	}
+	dims["priority_level"] = func(item services.DataItem) []string {
+		return []string{priority.Calculate(item, at).Level}
+	}
+	return dims
+}
+
+// Compute aggregates items by the groupBy dimensions and returns the
+// requested metrics for each group plus an overall total. Priority levels
+// are evaluated as of at.
+func Compute(items []services.DataItem, groupBy, metrics []string, at time.Time) (*Report, error) {
+	dims := dimensionsAt(at)
+	for _, g := range groupBy {
+		if _, ok := dims[g]; !ok {
+			return nil, errors.New("unknown group_by: " + g)
+		}
+	}
+	if len(metrics) == 0 {
+		metrics = DefaultMetrics
+	}
+	for _, m := range metrics {
+		if _, ok := sumMetrics[m]; ok {
+			continue
+		}
+		if _, ok := dims[m]; !ok {
+			return nil, errors.New("unknown metric: " + m)
+		}
+	}
+
+	total := newAccumulator(dims, metrics)
+	groups := make(map[string]*accumulator)
+	order := make([]string, 0)
+
+	for _, item := range items {
+		total.add(item)
+		for _, key := range groupKeys(dims, item, groupBy) {
+			id := strings.Join(key, "\x00")
+			acc, ok := groups[id]
+			if !ok {
+				acc = newAccumulator(dims, metrics)
+				acc.key = key
+				groups[id] = acc
+				order = append(order, id)
+			}
+			acc.add(item)
+		}
+	}
+
+	report := &Report{
+		GroupBy: groupBy,
+		Metrics: metrics,
+		Total:   total.row(nil),
+		Groups:  make([]Row, 0, len(groups)),
+	}
+	if len(groupBy) == 0 {
+		return report, nil
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return groups[order[i]].count > groups[order[j]].count
+	})
+	for _, id := range order {
+		acc := groups[id]
+		key := make(map[string]string, len(groupBy))
+		for i, g := range groupBy {
+			key[g] = acc.key[i]
+		}
+		report.Groups = append(report.Groups, acc.row(key))
+	}
+	return report, nil
+}
+
+// groupKeys returns every combination of dimension values for an item, so
+// an item with two disease categories is counted in both groups.
+func groupKeys(dims map[string]dimension, item services.DataItem, groupBy []string) [][]string {
+	keys := [][]string{{}}
+	for _, g := range groupBy {
+		values := dims[g](item)
+		next := make([][]string, 0, len(keys)*len(values))
+		for _, k := range keys {
+			for _, v := range values {
+				combined := append(append([]string{}, k...), v)
+				next = append(next, combined)
+			}
+		}
+		keys = next
+	}
+	return keys
+}
+
+type accumulator struct {
+	dims       map[string]dimension
+	key        []string
+	metrics    []string
+	count      int
+	sums       map[string]int
+	breakdowns map[string]map[string]int
+}
+
+func newAccumulator(dims map[string]dimension, metrics []string) *accumulator {
+	return &accumulator{
+		dims:       dims,
+		metrics:    metrics,
+		sums:       make(map[string]int),
+		breakdowns: make(map[string]map[string]int),
+	}
+}
+
+func (a *accumulator) add(item services.DataItem) {
+	a.count++
+	for _, m := range a.metrics {
+		if sum, ok := sumMetrics[m]; ok {
+			a.sums[m] += sum(item)
+			continue
+		}
+		if a.breakdowns[m] == nil {
+			a.breakdowns[m] = make(map[string]int)
+		}
+		for _, v := range a.dims[m](item) {
+			a.breakdowns[m][v]++
+		}
+	}
+}
+
+func (a *accumulator) row(key map[string]string) Row {
+	row := Row{}
+	if key != nil {
+		row["key"] = key
+	}
+	for _, m := range a.metrics {
+		if _, ok := sumMetrics[m]; ok {
+			row[m] = a.sums[m]
+		} else {
+			row[m] = a.breakdowns[m]
+		}
+	}
+	return row
+}
+
+var areaPath = []struct {
+	level areas.Level
+	get   func(services.LocationProperty) string
+}{
+	{areas.LevelProvince, func(p services.LocationProperty) string { return p.Province }},
+	{areas.LevelDistrict, func(p services.LocationProperty) string { return p.District }},
+	{areas.LevelSubdistrict, func(p services.LocationProperty) string { return p.SubDistrict }},
+}
+
+// areaDimension labels an item with the first depth levels of its area,
+// joined by "/" ("สงขลา/หาดใหญ่"), so districts and subdistricts that share
+// a name in different provinces are counted apart. Each name is resolved
+// through the gazetteer within the area above it; below a name that does
+// not resolve, names are kept as written.
+func areaDimension(depth int) dimension {
+	return func(item services.DataItem) []string {
+		gz := areas.Default()
+		parts := make([]string, 0, depth)
+		var parent *areas.Area
+		resolving := true
+		for _, lv := range areaPath[:depth] {
+			name := strings.TrimSpace(lv.get(item.Location.Properties))
+			if name == "" {
+				parts = append(parts, unknownValue)
+				resolving = false
+				continue
+			}
+			var area *areas.Area
+			ok := false
+			switch {
+			case !resolving:
+			case parent == nil:
+				area, ok = gz.Resolve(lv.level, name)
+			default:
+				area, ok = gz.ResolveUnder(parent, lv.level, name)
+			}
+			if !ok {
+				parts = append(parts, name)
+				resolving = false
+				continue
+			}
+			parts = append(parts, area.NameTH)
+			parent = area
+		}
+		return []string{strings.Join(parts, "/")}
+	}
+}
+
+func textDimension(get func(services.LocationProperty) string) dimension {
+	return func(item services.DataItem) []string {
+		val := strings.TrimSpace(get(item.Location.Properties))
+		if val == "" {
+			return []string{unknownValue}
+		}
+		return []string{val}
+	}
+}
+
+func lifecycleDimension(item services.DataItem) []string {
+	return []string{string(lifecycle.Classify(item.Location.Properties.StatusText))}
+}
+
+func categoryDimension(item services.DataItem) []string {
+	prop := item.Location.Properties
+	categories := category.Classify(prop.TypeName, prop.Other)
+	if len(categories) == 0 {
+		return []string{unknownValue}
+	}
+	values := make([]string, len(categories))
+	for i, c := range categories {
+		values[i] = string(c)
+	}
+	return values
+}
+
+func sickLevelDimension(item services.DataItem) []string {
+	return []string{strconv.Itoa(item.Location.Properties.SickLevelSummary)}
+}
+
+// ageBandDimension puts an item in the band of every age listed for it.
+func ageBandDimension(item services.DataItem) []string {
+	ages := priority.ParseAges(item.Location.Properties.Ages)
+	if len(ages) == 0 {
+		return []string{unknownValue}
+	}
+	bands := make([]string, 0, len(ages))
+	seen := make(map[string]bool, len(ages))
+	for _, age := range ages {
+		band := ageBand(age)
+		if !seen[band] {
+			seen[band] = true
+			bands = append(bands, band)
+		}
+	}
+	return bands
+}
+
+func ageBand(age float64) string {
+	switch {
+	case age < 6:
+		return "0-5"
+	case age < 18:
+		return "6-17"
+	case age < 60:
+		return "18-59"
+	case age < 70:
+		return "60-69"
+	default:
+		return "70+"
+	}
+}
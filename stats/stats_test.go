@@ -0,0 +1,36 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/Nxdus/hatyai-api/services"
+)
+
+func TestAreaDimension(t *testing.T) {
+	tests := []struct {
+		name                            string
+		province, district, subdistrict string
+		depth                           int
+		want                            string
+	}{
+		{"province", "สงขลา", "", "", 1, "สงขลา"},
+		{"district under province", "Songkhla", "Hat Yai", "", 2, "สงขลา/หาดใหญ่"},
+		{"subdistrict path", "สงขลา", "หาดใหญ่", "คอหงส์", 3, "สงขลา/หาดใหญ่/คอหงส์"},
+		{"unknown district", "สงขลา", "เมืองปัตตานี", "", 2, "สงขลา/เมืองปัตตานี"},
+		{"unresolved province", "Atlantis", "Hat Yai", "", 2, "Atlantis/Hat Yai"},
+		{"missing province", "", "หาดใหญ่", "", 2, "unknown/หาดใหญ่"},
+		{"missing district", "สงขลา", "", "คอหงส์", 3, "สงขลา/unknown/คอหงส์"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var item services.DataItem
+			item.Location.Properties.Province = tt.province
+			item.Location.Properties.District = tt.district
+			item.Location.Properties.SubDistrict = tt.subdistrict
+			got := areaDimension(tt.depth)(item)
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("areaDimension(%d) = %q, want %q", tt.depth, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package textnorm
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalize folds text into the form used for matching: Unicode NFKC
+// (which also folds full-width and other compatibility forms), invisible
+// characters removed, Thai digits converted to ASCII, Thai combining marks
+// put in a single canonical order, sara am composed, lower case, and runs
+// of whitespace collapsed to one space.
+func Normalize(s string) string {
+	chars := normalize(s)
+	runes := make([]rune, len(chars))
+	for i, c := range chars {
+		runes[i] = c.r
+	}
+	return string(runes)
+}
+
+// Source is the byte range of the original text a piece of normalised text
+// came from.
+type Source struct {
+	Start, End int
+}
+
+// NormalizeSource is Normalize that also returns, for every byte of the
+// result, the range of s it came from, so matches found in normalised text
+// can be shown in the text as written.
+func NormalizeSource(s string) (string, []Source) {
+	chars := normalize(s)
+	var b strings.Builder
+	sources := make([]Source, 0, len(s))
+	for _, c := range chars {
+		n, _ := b.WriteRune(c.r)
+		for i := 0; i < n; i++ {
+			sources = append(sources, Source{c.start, c.end})
+		}
+	}
+	return b.String(), sources
+}
+
+// char is a rune of normalised text with the byte range of s it came from.
+type char struct {
+	r          rune
+	start, end int
+}
+
+func normalize(s string) []char {
+	if s == "" {
+		return nil
+	}
+
+	var it norm.Iter
+	it.InitString(norm.NFKC, s)
+	chars := make([]char, 0, len(s))
+	for !it.Done() {
+		start := it.Pos()
+		seg := string(it.Next())
+		end := it.Pos()
+		for _, r := range seg {
+			switch {
+			case isInvisible(r):
+				continue
+			case r >= '๐' && r <= '๙':
+				r = '0' + (r - '๐')
+			case unicode.IsSpace(r):
+				r = ' '
+			default:
+				r = unicode.ToLower(r)
+			}
+			chars = append(chars, char{r: r, start: start, end: end})
+		}
+	}
+
+	chars = reorderMarks(chars)
+	chars = composeSaraAm(chars)
+	return collapseSpaces(chars)
+}
+
+// Compact is Normalize with the spaces that sit next to Thai text removed.
+// Thai is written without spaces between words, so "หาย ใจ ไม่ ออก" and
+// "หายใจไม่ออก" compact to the same string, while spacing between Latin
+// words is kept.
+func Compact(s string) string {
+	norm := Normalize(s)
+	if !strings.Contains(norm, " ") {
+		return norm
+	}
+
+	runes := []rune(norm)
+	out := make([]rune, 0, len(runes))
+	for i, r := range runes {
+		if r == ' ' && i > 0 && i < len(runes)-1 && (IsThai(runes[i-1]) || IsThai(runes[i+1])) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// Contains reports whether sub occurs in text once both are compacted.
+func Contains(text, sub string) bool {
+	sub = Compact(sub)
+	if sub == "" {
+		return false
+	}
+	return strings.Contains(Compact(text), sub)
+}
+
+// ContainsUnnegated reports whether kw occurs in key other than right after
+// one of negations, ignoring spaces between them. All three are expected to
+// be compacted already. A Latin negation must be a whole word: "no" ends
+// "casino".
+func ContainsUnnegated(key, kw string, negations []string) bool {
+	for from := 0; ; {
+		i := strings.Index(key[from:], kw)
+		if i < 0 {
+			return false
+		}
+		i += from
+		if !negated(key[:i], negations) {
+			return true
+		}
+		from = i + len(kw)
+	}
+}
+
+func negated(before string, negations []string) bool {
+	before = strings.TrimRight(before, " ")
+	for _, neg := range negations {
+		if !strings.HasSuffix(before, neg) {
+			continue
+		}
+		rest := before[:len(before)-len(neg)]
+		if isLatin(neg) && rest != "" && !strings.HasSuffix(rest, " ") {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func isLatin(s string) bool {
+	for _, r := range s {
+		if r >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func IsThai(r rune) bool {
+	return r >= 0x0E00 && r <= 0x0E7F
+}
+
+// isInvisible covers zero-width spaces and joiners, the word joiner, BOM
+// and soft hyphen, all of which are Unicode format characters.
+func isInvisible(r rune) bool {
+	return unicode.Is(unicode.Cf, r)
+}
+
+// Thai combining marks that stack on a consonant. Typists enter them in
+// either order and they render the same, so they are sorted by class.
+func markClass(r rune) int {
+	switch {
+	case r == 0x0E31, r >= 0x0E34 && r <= 0x0E3A, r == 0x0E47:
+		return 1 // above and below vowels, mai taikhu
+	case r >= 0x0E48 && r <= 0x0E4B:
+		return 2 // tone marks
+	case r >= 0x0E4C && r <= 0x0E4E:
+		return 3 // thanthakhat, nikhahit, yamakkan
+	}
+	return 0
+}
+
+func reorderMarks(chars []char) []char {
+	out := make([]char, 0, len(chars))
+	for i := 0; i < len(chars); {
+		if markClass(chars[i].r) == 0 {
+			out = append(out, chars[i])
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(chars) && markClass(chars[j].r) != 0 {
+			j++
+		}
+		marks := append([]char(nil), chars[i:j]...)
+		sortMarks(marks)
+		for k, m := range marks {
+			if k > 0 && marks[k-1].r == m.r {
+				continue
+			}
+			out = append(out, m)
+		}
+		i = j
+	}
+	return out
+}
+
+func sortMarks(marks []char) {
+	for i := 1; i < len(marks); i++ {
+		for j := i; j > 0 && markClass(marks[j].r) < markClass(marks[j-1].r); j-- {
+			marks[j], marks[j-1] = marks[j-1], marks[j]
+		}
+	}
+}
+
+// composeSaraAm turns nikhahit + sara aa into sara am, and moves a tone mark
+// typed after sara am back onto the consonant ("นำ้" → "น้ำ").
+func composeSaraAm(chars []char) []char {
+	out := make([]char, 0, len(chars))
+	for i := 0; i < len(chars); i++ {
+		c := chars[i]
+		if c.r == 0x0E4D && i+1 < len(chars) && chars[i+1].r == 0x0E32 {
+			c = char{r: 0x0E33, start: c.start, end: chars[i+1].end}
+			i++
+		}
+		if c.r == 0x0E33 && i+1 < len(chars) && markClass(chars[i+1].r) == 2 {
+			out = append(out, chars[i+1], c)
+			i++
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// collapseSpaces drops leading and trailing spaces and keeps one space of
+// every run.
+func collapseSpaces(chars []char) []char {
+	out := chars[:0]
+	for _, c := range chars {
+		if c.r == ' ' && (len(out) == 0 || out[len(out)-1].r == ' ') {
+			continue
+		}
+		out = append(out, c)
+	}
+	if n := len(out); n > 0 && out[n-1].r == ' ' {
+		out = out[:n-1]
+	}
+	return out
+}
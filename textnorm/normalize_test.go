@@ -0,0 +1,86 @@
+package textnorm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"empty", "", ""},
+		{"whitespace", "  หาย  ใจ\tไม่ออก \n", "หาย ใจ ไม่ออก"},
+		{"zero width space", "หาย\u200bใจ", "หายใจ"},
+		{"soft hyphen and BOM", "\ufeffติด\u00adเตียง", "ติดเตียง"},
+		{"thai digits", "อายุ ๔๕", "อายุ 45"},
+		{"full width", "ＳＯＳ\u3000１２", "sos 12"},
+		{"lower case", "Hat Yai", "hat yai"},
+		{"tone before vowel", "ท่ี", "ที่"},
+		{"doubled tone", "ที่่", "ที่"},
+		{"tone after sara am", "นำ้", "น้ำ"},
+		{"decomposed sara am", "น\u0e49\u0e4d\u0e32", "น้ำ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.in); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompact(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"หาย ใจ ไม่ ออก", "หายใจไม่ออก"},
+		{"หาย\u200b ใจ", "หายใจ"},
+		{"Hat  Yai", "hat yai"},
+		{"ติดเตียง bed ridden", "ติดเตียงbed ridden"},
+		{" ", ""},
+	}
+	for _, tt := range tests {
+		if got := Compact(tt.in); got != tt.want {
+			t.Errorf("Compact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	if !Contains("ผู้ป่วย หาย ใจ ไม่ ออก", "หายใจไม่ออก") {
+		t.Error("spaced Thai text should contain the compact keyword")
+	}
+	if Contains("หายใจได้", "") {
+		t.Error("nothing should match an empty keyword")
+	}
+}
+
+func TestNormalizeSource(t *testing.T) {
+	tests := []struct {
+		in, sub, want string
+	}{
+		{"Need  INSULIN", "insulin", "INSULIN"},
+		{"ต้องการน้ำ ๒ ลัง", "2", "๒"},
+		{"ＡＢＣ ยา", "abc", "ＡＢＣ"},
+		{"หาย\u200bใจ", "หายใจ", "หาย\u200bใจ"},
+		{"นํ้า", "น้ำ", "นํ้า"},
+	}
+	for _, tt := range tests {
+		norm, sources := NormalizeSource(tt.in)
+		if len(sources) != len(norm) {
+			t.Fatalf("NormalizeSource(%q) gave %d sources for %d bytes", tt.in, len(sources), len(norm))
+		}
+		i := strings.Index(norm, tt.sub)
+		if i < 0 {
+			t.Fatalf("Normalize(%q) = %q, does not contain %q", tt.in, norm, tt.sub)
+		}
+		start, end := sources[i].Start, sources[i].End
+		for _, src := range sources[i : i+len(tt.sub)] {
+			start, end = min(start, src.Start), max(end, src.End)
+		}
+		if got := tt.in[start:end]; got != tt.want {
+			t.Errorf("source of %q in %q = %q, want %q", tt.sub, tt.in, got, tt.want)
+		}
+	}
+}
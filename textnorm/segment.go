@@ -0,0 +1,212 @@
+package textnorm
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed words.txt
+var seedWords []byte
+
+// Token is one word of a normalised string. Start and End are byte offsets
+// into the string returned by Normalize.
+type Token struct {
+	Text  string `json:"text"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Known bool   `json:"known"`
+}
+
+// Dictionary is a word list for segmentation. It is not modified once
+// built; With returns an extended copy.
+type Dictionary struct {
+	words  map[string]struct{}
+	maxLen int
+}
+
+var defaultDict = newDictionary()
+
+func newDictionary() *Dictionary {
+	d := &Dictionary{words: make(map[string]struct{})}
+	scanner := bufio.NewScanner(bytes.NewReader(seedWords))
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		d.add(string(line))
+	}
+	return d
+}
+
+// DefaultDictionary returns the built-in word list.
+func DefaultDictionary() *Dictionary {
+	return defaultDict
+}
+
+// With returns a copy of d that also knows words, e.g. the keywords of a
+// rule file so they are never split apart. d itself is unchanged.
+func (d *Dictionary) With(words ...string) *Dictionary {
+	c := &Dictionary{words: make(map[string]struct{}, len(d.words)+len(words)), maxLen: d.maxLen}
+	for w := range d.words {
+		c.words[w] = struct{}{}
+	}
+	for _, w := range words {
+		c.add(w)
+	}
+	return c
+}
+
+func (d *Dictionary) add(word string) {
+	word = Compact(word)
+	if word == "" {
+		return
+	}
+	d.words[word] = struct{}{}
+	if n := utf8.RuneCountInString(word); n > d.maxLen {
+		d.maxLen = n
+	}
+}
+
+// Segment splits text into words with the built-in dictionary.
+func Segment(text string) []Token {
+	return defaultDict.Segment(text)
+}
+
+// Segment splits text into words. Thai runs are cut with the dictionary,
+// preferring the fewest unknown characters and then the fewest words;
+// everything else is split on spaces and punctuation. A nil dictionary
+// is the built-in one.
+func (d *Dictionary) Segment(text string) []Token {
+	if d == nil {
+		d = defaultDict
+	}
+	norm := Normalize(text)
+	tokens := make([]Token, 0)
+
+	start := -1
+	thai := false
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		if thai {
+			tokens = append(tokens, d.segmentThai(norm, start, end)...)
+		} else {
+			tokens = append(tokens, Token{Text: norm[start:end], Start: start, End: end})
+		}
+		start = -1
+	}
+
+	for i, r := range norm {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || IsThai(r)
+		if !isWord {
+			flush(i)
+			continue
+		}
+		rThai := IsThai(r)
+		if start >= 0 && rThai != thai {
+			flush(i)
+		}
+		if start < 0 {
+			start, thai = i, rThai
+		}
+	}
+	flush(len(norm))
+	return tokens
+}
+
+// segmentThai runs maximal matching over norm[from:to], which holds Thai
+// text with no spaces.
+func (d *Dictionary) segmentThai(norm string, from, to int) []Token {
+	runes := []rune(norm[from:to])
+	offsets := make([]int, len(runes)+1)
+	off := from
+	for i, r := range runes {
+		offsets[i] = off
+		off += utf8.RuneLen(r)
+	}
+	offsets[len(runes)] = off
+
+	type step struct {
+		unknown, words int
+		prev           int
+		known          bool
+	}
+	const inf = 1 << 30
+	best := make([]step, len(runes)+1)
+	for i := 1; i <= len(runes); i++ {
+		best[i] = step{unknown: inf, words: inf}
+	}
+
+	better := func(a, b step) bool {
+		if a.unknown != b.unknown {
+			return a.unknown < b.unknown
+		}
+		return a.words < b.words
+	}
+
+	for i := 0; i < len(runes); i++ {
+		if best[i].unknown == inf || !canBreak(runes, i) {
+			continue
+		}
+		for j := i + 1; j <= len(runes) && j-i <= d.maxLen; j++ {
+			if !canBreak(runes, j) {
+				continue
+			}
+			if _, ok := d.words[string(runes[i:j])]; ok {
+				cand := step{unknown: best[i].unknown, words: best[i].words + 1, prev: i, known: true}
+				if better(cand, best[j]) {
+					best[j] = cand
+				}
+			}
+		}
+
+		j := nextBreak(runes, i)
+		cand := step{unknown: best[i].unknown + (j - i), words: best[i].words + 1, prev: i}
+		if better(cand, best[j]) {
+			best[j] = cand
+		}
+	}
+
+	tokens := make([]Token, 0)
+	for j := len(runes); j > 0; j = best[j].prev {
+		i := best[j].prev
+		tok := Token{Text: string(runes[i:j]), Start: offsets[i], End: offsets[j], Known: best[j].known}
+		if n := len(tokens); n > 0 && !tok.Known && !tokens[n-1].Known {
+			last := tokens[n-1]
+			tokens[n-1] = Token{Text: tok.Text + last.Text, Start: tok.Start, End: last.End}
+			continue
+		}
+		tokens = append(tokens, tok)
+	}
+	for i, j := 0, len(tokens)-1; i < j; i, j = i+1, j-1 {
+		tokens[i], tokens[j] = tokens[j], tokens[i]
+	}
+	return tokens
+}
+
+// canBreak reports whether a word may start at runes[i]: not before a
+// combining mark or following vowel, and not after a leading vowel.
+func canBreak(runes []rune, i int) bool {
+	if i == 0 || i == len(runes) {
+		return true
+	}
+	r := runes[i]
+	if markClass(r) != 0 || r == 0x0E30 || r == 0x0E32 || r == 0x0E33 || r == 0x0E45 {
+		return false
+	}
+	prev := runes[i-1]
+	return prev < 0x0E40 || prev > 0x0E44
+}
+
+func nextBreak(runes []rune, i int) int {
+	j := i + 1
+	for j < len(runes) && !canBreak(runes, j) {
+		j++
+	}
+	return j
+}
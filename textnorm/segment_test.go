@@ -0,0 +1,54 @@
+package textnorm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func words(tokens []Token) []string {
+	out := make([]string, len(tokens))
+	for i, tok := range tokens {
+		out[i] = tok.Text
+	}
+	return out
+}
+
+func TestSegment(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"ผู้ป่วยติดเตียง", []string{"ผู้ป่วย", "ติดเตียง"}},
+		{"หาย ใจ ไม่ ออก", []string{"หาย", "ใจ", "ไม่", "ออก"}},
+		{"ไม่ได้หมดสติ", []string{"ไม่ได้", "หมดสติ"}},
+		{"ต้องการเรือ 2 ลำ", []string{"ต้องการ", "เรือ", "2", "ลำ"}},
+		{"need boat, ด่วน!", []string{"need", "boat", "ด่วน"}},
+		{"ติดเตียงbed", []string{"ติดเตียง", "bed"}},
+		{"", []string{}},
+	}
+	for _, tt := range tests {
+		if got := words(Segment(tt.in)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Segment(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSegmentOffsets(t *testing.T) {
+	text := "ผู้ป่วย ติดเตียง"
+	norm := Normalize(text)
+	for _, tok := range Segment(text) {
+		if norm[tok.Start:tok.End] != tok.Text {
+			t.Errorf("token %q has offsets %d:%d covering %q", tok.Text, tok.Start, tok.End, norm[tok.Start:tok.End])
+		}
+	}
+}
+
+func TestDictionaryWith(t *testing.T) {
+	extended := DefaultDictionary().With("ซอยพิเศษ")
+	if got := words(extended.Segment("ซอยพิเศษ")); !reflect.DeepEqual(got, []string{"ซอยพิเศษ"}) {
+		t.Errorf("extended dictionary split its own word: %q", got)
+	}
+	if tokens := Segment("ซอยพิเศษ"); len(tokens) == 1 && tokens[0].Known {
+		t.Error("With changed the built-in dictionary")
+	}
+}
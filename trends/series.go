@@ -0,0 +1,96 @@
+package trends
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Bucket struct {
+	Start   int64 `json:"t"`
+	Value   int   `json:"value"`
+	Min     int   `json:"min"`
+	Max     int   `json:"max"`
+	Samples int   `json:"samples"`
+}
+
+// Series reduces points to one bucket per interval for the given province,
+// or the whole feed when province is empty, and metric. Counts are gauges,
+// so a bucket reports the last sample it saw along with the range.
+//
+// metric is "total", "level:<priority level>" or "status:<status_text>".
+func Series(points []Point, province, metric string, interval time.Duration) ([]Bucket, error) {
+	if interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+	pick, err := metricFunc(metric)
+	if err != nil {
+		return nil, err
+	}
+	province = provinceName(province)
+
+	step := int64(interval / time.Second)
+	buckets := make([]Bucket, 0)
+	for _, p := range points {
+		counts := &p.Counts
+		if province != "" {
+			c, ok := p.Provinces[province]
+			if !ok {
+				c = &Counts{}
+			}
+			counts = c
+		}
+		val := pick(counts)
+		start := p.At - p.At%step
+
+		if n := len(buckets); n > 0 && buckets[n-1].Start == start {
+			b := &buckets[n-1]
+			b.Value = val
+			b.Min = min(b.Min, val)
+			b.Max = max(b.Max, val)
+			b.Samples++
+			continue
+		}
+		buckets = append(buckets, Bucket{Start: start, Value: val, Min: val, Max: val, Samples: 1})
+	}
+	return buckets, nil
+}
+
+func metricFunc(metric string) (func(*Counts) int, error) {
+	metric = strings.TrimSpace(metric)
+	if metric == "" || metric == "total" {
+		return func(c *Counts) int { return c.Total }, nil
+	}
+
+	kind, val, ok := strings.Cut(metric, ":")
+	if !ok || strings.TrimSpace(val) == "" {
+		return nil, errors.New("metric must be total, level:<level> or status:<status_text>")
+	}
+	val = strings.TrimSpace(val)
+	switch strings.ToLower(kind) {
+	case "level":
+		val = strings.ToLower(val)
+		return func(c *Counts) int { return c.Levels[val] }, nil
+	case "status":
+		return func(c *Counts) int { return c.Statuses[val] }, nil
+	}
+	return nil, errors.New("unknown metric: " + metric)
+}
+
+// ParseInterval accepts Go durations plus a "d" suffix for days.
+func ParseInterval(val string) (time.Duration, error) {
+	val = strings.TrimSpace(val)
+	if strings.HasSuffix(val, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(val, "d"))
+		if err != nil {
+			return 0, errors.New("invalid interval: " + val)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, errors.New("invalid interval: " + val)
+	}
+	return d, nil
+}
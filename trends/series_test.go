@@ -0,0 +1,124 @@
+package trends
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Nxdus/hatyai-api/services"
+)
+
+func point(at int64, total int, provinces map[string]int) Point {
+	p := Point{At: at, Counts: Counts{Total: total, Levels: map[string]int{"high": total}}, Provinces: map[string]*Counts{}}
+	for name, n := range provinces {
+		p.Provinces[name] = &Counts{Total: n}
+	}
+	return p
+}
+
+func TestSeries(t *testing.T) {
+	points := []Point{
+		point(3600, 5, map[string]int{"สงขลา": 2}),
+		point(4500, 9, map[string]int{"สงขลา": 4}),
+		point(5400, 7, nil),
+		point(7300, 3, map[string]int{"สงขลา": 1}),
+	}
+	tests := []struct {
+		name     string
+		province string
+		metric   string
+		interval time.Duration
+		want     []Bucket
+	}{
+		{"last value with range per hour", "", "total", time.Hour, []Bucket{
+			{Start: 3600, Value: 7, Min: 5, Max: 9, Samples: 3},
+			{Start: 7200, Value: 3, Min: 3, Max: 3, Samples: 1},
+		}},
+		{"one bucket per point", "", "", 15 * time.Minute, []Bucket{
+			{Start: 3600, Value: 5, Min: 5, Max: 5, Samples: 1},
+			{Start: 4500, Value: 9, Min: 9, Max: 9, Samples: 1},
+			{Start: 5400, Value: 7, Min: 7, Max: 7, Samples: 1},
+			{Start: 7200, Value: 3, Min: 3, Max: 3, Samples: 1},
+		}},
+		{"province by English name, missing counts as zero", "Songkhla", "total", time.Hour, []Bucket{
+			{Start: 3600, Value: 0, Min: 0, Max: 4, Samples: 3},
+			{Start: 7200, Value: 1, Min: 1, Max: 1, Samples: 1},
+		}},
+		{"level metric", "", "level:HIGH", 2 * time.Hour, []Bucket{
+			{Start: 0, Value: 7, Min: 5, Max: 9, Samples: 3},
+			{Start: 7200, Value: 3, Min: 3, Max: 3, Samples: 1},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Series(points, tt.province, tt.metric, tt.interval)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Series = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeriesRejects(t *testing.T) {
+	for _, tt := range []struct {
+		metric   string
+		interval time.Duration
+	}{
+		{"total", 0},
+		{"level:", time.Hour},
+		{"size:large", time.Hour},
+	} {
+		if _, err := Series(nil, "", tt.metric, tt.interval); err == nil {
+			t.Errorf("Series(%q, %v) accepted", tt.metric, tt.interval)
+		}
+	}
+}
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"15m", 15 * time.Minute, true},
+		{"1h", time.Hour, true},
+		{"2d", 48 * time.Hour, true},
+		{"d", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseInterval(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseInterval(%q) = %v, %v; want %v, ok %v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	var data services.APIResponse
+	data.FetchedAt = "2025-11-28T10:00:00Z"
+	for _, p := range []struct{ province, status string }{
+		{"Songkhla", "รอรับเรื่อง"},
+		{"จ.สงขลา", "ช่วยเหลือแล้ว"},
+		{"", "รอรับเรื่อง"},
+	} {
+		var item services.DataItem
+		item.Location.Properties.Province = p.province
+		item.Location.Properties.StatusText = p.status
+		data.Data.Data = append(data.Data.Data, item)
+	}
+
+	point := Summarize(&data)
+	if point.At != time.Date(2025, 11, 28, 10, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("At = %d, want the snapshot time", point.At)
+	}
+	if point.Counts.Total != 3 || point.Counts.Statuses["รอรับเรื่อง"] != 2 {
+		t.Errorf("Counts = %+v, want 3 items, 2 waiting", point.Counts)
+	}
+	if len(point.Provinces) != 1 || point.Provinces["สงขลา"] == nil || point.Provinces["สงขลา"].Total != 2 {
+		t.Errorf("Provinces = %v, want สงขลา with 2 items", point.Provinces)
+	}
+}
@@ -0,0 +1,141 @@
+package trends
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Nxdus/hatyai-api/areas"
+	"github.com/Nxdus/hatyai-api/priority"
+	"github.com/Nxdus/hatyai-api/services"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisKeyTrends = "request:trends"
+	retention      = 14 * 24 * time.Hour
+)
+
+type Counts struct {
+	Total    int            `json:"total"`
+	Levels   map[string]int `json:"levels"`
+	Statuses map[string]int `json:"statuses"`
+}
+
+// Point is the set of counts recorded for one upstream snapshot.
+type Point struct {
+	At        int64              `json:"at"`
+	Counts    Counts             `json:"counts"`
+	Provinces map[string]*Counts `json:"provinces"`
+}
+
+type Store struct {
+	redis *redis.Client
+}
+
+func NewStore(redis *redis.Client) *Store {
+	return &Store{redis: redis}
+}
+
+// Record stores the counts for a snapshot in a sorted set scored by the
+// snapshot time. A point already recorded for that time is replaced, so
+// instances recording the same snapshot leave a single point.
+func (s *Store) Record(data *services.APIResponse) {
+	if data == nil {
+		return
+	}
+
+	point := Summarize(data)
+	member, err := json.Marshal(point)
+	if err != nil {
+		log.Printf("marshal trend point failed: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cutoff := time.Unix(point.At, 0).Add(-retention).Unix()
+	at := strconv.FormatInt(point.At, 10)
+	pipe := s.redis.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, redisKeyTrends, at, at)
+	pipe.ZAdd(ctx, redisKeyTrends, redis.Z{Score: float64(point.At), Member: member})
+	pipe.ZRemRangeByScore(ctx, redisKeyTrends, "-inf", "("+strconv.FormatInt(cutoff, 10))
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("failed to record trend point key=%s: %v", redisKeyTrends, err)
+	}
+}
+
+func (s *Store) Points(from, to time.Time) ([]Point, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	vals, err := s.redis.ZRangeByScore(ctx, redisKeyTrends, &redis.ZRangeBy{
+		Min: strconv.FormatInt(from.Unix(), 10),
+		Max: strconv.FormatInt(to.Unix(), 10),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]Point, 0, len(vals))
+	for _, v := range vals {
+		var p Point
+		if json.Unmarshal([]byte(v), &p) == nil {
+			points = append(points, p)
+		}
+	}
+	return points, nil
+}
+
+func Summarize(data *services.APIResponse) Point {
+	at := data.FetchedTime()
+	point := Point{
+		At:        at.Unix(),
+		Counts:    newCounts(),
+		Provinces: make(map[string]*Counts),
+	}
+	for _, item := range data.Data.Data {
+		prop := item.Location.Properties
+		level := priority.Calculate(item, at).Level
+		status := strings.TrimSpace(prop.StatusText)
+
+		point.Counts.add(level, status)
+
+		province := provinceName(prop.Province)
+		if province == "" {
+			continue
+		}
+		c, ok := point.Provinces[province]
+		if !ok {
+			nc := newCounts()
+			c = &nc
+			point.Provinces[province] = c
+		}
+		c.add(level, status)
+	}
+	return point
+}
+
+func newCounts() Counts {
+	return Counts{Levels: make(map[string]int), Statuses: make(map[string]int)}
+}
+
+func (c *Counts) add(level, status string) {
+	c.Total++
+	c.Levels[level]++
+	if status != "" {
+		c.Statuses[status]++
+	}
+}
+
+func provinceName(name string) string {
+	name = strings.TrimSpace(name)
+	if area, ok := areas.Default().Resolve(areas.LevelProvince, name); ok {
+		return area.NameTH
+	}
+	return name
+}
@@ -0,0 +1,52 @@
+package trends
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Nxdus/hatyai-api/services"
+	"github.com/redis/go-redis/v9"
+)
+
+// testStore connects to the Redis server at REDIS_TEST_ADDR, using a
+// database of its own, and skips the test when none is given.
+func testStore(t *testing.T) *Store {
+	addr := os.Getenv("REDIS_TEST_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_TEST_ADDR not set")
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: addr, DB: 15})
+	ctx := context.Background()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		t.Skipf("redis at %s unavailable: %v", addr, err)
+	}
+	rdb.Del(ctx, redisKeyTrends)
+	t.Cleanup(func() {
+		rdb.Del(context.Background(), redisKeyTrends)
+		rdb.Close()
+	})
+	return NewStore(rdb)
+}
+
+func TestRecordReplacesSnapshot(t *testing.T) {
+	s := testStore(t)
+	at := time.Now().UTC().Truncate(time.Second)
+	snapshot := func(n int) *services.APIResponse {
+		data := &services.APIResponse{FetchedAt: at.Format(time.RFC3339)}
+		data.Data.Data = make([]services.DataItem, n)
+		return data
+	}
+
+	s.Record(snapshot(2))
+	s.Record(snapshot(5))
+
+	points, err := s.Points(at.Add(-time.Minute), at.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 1 || points[0].Counts.Total != 5 {
+		t.Errorf("points = %+v, want one point with the later counts", points)
+	}
+}